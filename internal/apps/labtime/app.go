@@ -16,7 +16,6 @@ import (
 	"aireone.xyz/labtime/internal/watcher"
 	"aireone.xyz/labtime/internal/yamlconfig"
 	"github.com/pkg/errors"
-	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"golang.org/x/sync/errgroup"
 )
@@ -111,7 +110,7 @@ type Container struct {
 	LabtimeLabel bool
 }
 
-func setupDynamicDockerMonitoring(container Container, s *scheduler.Scheduler, mc *monitorconfig.MonitorConfig[monitors.DockerTarget, *prometheus.GaugeVec], logger *log.Logger) error {
+func setupDynamicDockerMonitoring(container Container, s *scheduler.Scheduler, mc *monitorconfig.MonitorConfig[monitors.DockerTarget, *monitors.DockerCollector], logger *log.Logger) error {
 	if !container.LabtimeLabel {
 		return nil
 	}
@@ -188,7 +187,7 @@ func (a *App) Start(ctx context.Context) error {
 			return errors.Wrap(err, "error listing running containers for dynamic docker monitoring")
 		}
 
-		mc, ok := a.monitorConfigs["docker"].(*monitorconfig.MonitorConfig[monitors.DockerTarget, *prometheus.GaugeVec])
+		mc, ok := a.monitorConfigs["docker"].(*monitorconfig.MonitorConfig[monitors.DockerTarget, *monitors.DockerCollector])
 		if !ok {
 			panic("docker monitor config not found or wrong type")
 		}
@@ -223,7 +222,7 @@ func (a *App) Start(ctx context.Context) error {
 					a.logger.Println("Docker event received")
 
 					if event.Action == "create" && event.Type == "container" && event.Actor.Attributes["labtime"] == "true" {
-						mc, ok := a.monitorConfigs["docker"].(*monitorconfig.MonitorConfig[monitors.DockerTarget, *prometheus.GaugeVec])
+						mc, ok := a.monitorConfigs["docker"].(*monitorconfig.MonitorConfig[monitors.DockerTarget, *monitors.DockerCollector])
 						if !ok {
 							panic("docker monitor config not found or wrong type")
 						}
@@ -18,9 +18,13 @@ func getMonitorConfigs() MonitorConfigs {
 			monitors.TLSMonitorFactory{},
 			monitors.TLSTargetProvider{},
 		),
+		"cert_files": monitorconfig.NewMonitorConfig(
+			monitors.CertFilesMonitorFactory{},
+			monitors.CertFilesTargetProvider{},
+		),
 		"docker": monitorconfig.NewMonitorConfig(
-			monitors.DockerMonitorFactory{},
-			monitors.DockerTargetProvider{},
+			monitors.NewDockerMonitorFactory(),
+			monitors.DockerTargetProvider{Hosts: monitors.NewDockerHosts()},
 		),
 	}
 }
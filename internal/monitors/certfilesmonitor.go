@@ -0,0 +1,298 @@
+package monitors
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"aireone.xyz/labtime/internal/yamlconfig"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// CertFilesTarget represents a certificate files monitoring target.
+type CertFilesTarget struct {
+	Name     string   `yaml:"name"`
+	Paths    []string `yaml:"paths"`
+	Interval int      `yaml:"interval,omitempty"`
+}
+
+// GetName implements the Target interface.
+func (c CertFilesTarget) GetName() string {
+	return c.Name
+}
+
+// GetInterval implements the Target interface.
+func (c CertFilesTarget) GetInterval() int {
+	return c.Interval
+}
+
+// CertFilesMonitorFactory implements MonitorFactory for certificate files
+// monitoring.
+type CertFilesMonitorFactory struct{}
+
+// CertFilesCollector groups the Prometheus metrics exported by the
+// certificate files monitors.
+type CertFilesCollector struct {
+	ExpiresTime   *prometheus.GaugeVec
+	CertNotAfter  *prometheus.GaugeVec
+	CertNotBefore *prometheus.GaugeVec
+
+	multiCollector
+}
+
+// CreateCollector creates the Prometheus collectors for certificate files
+// monitoring.
+func (c CertFilesMonitorFactory) CreateCollector() *CertFilesCollector {
+	labels := append([]string{"cert_files_monitor_name", "cert_file_path"}, certificateLabelNames...)
+	collector := &CertFilesCollector{
+		ExpiresTime: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "labtime_cert_file_expire_time",
+			Help: "The duration (in second) until the certificate read from the file expires.",
+		}, labels),
+		CertNotAfter: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "labtime_cert_file_not_after",
+			Help: "The expiration timestamp (in seconds since epoch) of the certificate read from the file.",
+		}, labels),
+		CertNotBefore: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "labtime_cert_file_not_before",
+			Help: "The start of validity timestamp (in seconds since epoch) of the certificate read from the file.",
+		}, labels),
+	}
+	collector.multiCollector = multiCollector{collector.ExpiresTime, collector.CertNotAfter, collector.CertNotBefore}
+	return collector
+}
+
+// CreateMonitor creates a certificate files monitor instance.
+func (c CertFilesMonitorFactory) CreateMonitor(target CertFilesTarget, collector *CertFilesCollector, logger *log.Logger) Job {
+	return &CertFilesMonitor{
+		Label:   target.Name,
+		Paths:   target.Paths,
+		Logger:  logger,
+		Metrics: collector,
+	}
+}
+
+// CertFilesTargetProvider implements TargetProvider for certificate files
+// targets.
+type CertFilesTargetProvider struct{}
+
+// GetTargets extracts certificate files targets from the configuration.
+func (c CertFilesTargetProvider) GetTargets(config *yamlconfig.YamlConfig) ([]CertFilesTarget, error) {
+	targets := make([]CertFilesTarget, len(config.CertFilesMonitors))
+	for i, monitor := range config.CertFilesMonitors {
+		name := monitor.Name
+		if name == "" && len(monitor.Paths) > 0 {
+			name = monitor.Paths[0]
+		}
+		if name == "" {
+			return nil, errors.Errorf("no name and no paths for cert_files target %d", i)
+		}
+		if len(monitor.Paths) == 0 {
+			return nil, errors.Errorf("no paths for target '%s'", name)
+		}
+		for _, pattern := range monitor.Paths {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return nil, errors.Wrapf(err, "invalid path pattern '%s' for target '%s'", pattern, name)
+			}
+		}
+		interval := monitor.Interval
+		if interval == 0 {
+			interval = 60
+		}
+		targets[i] = CertFilesTarget{
+			Name:     name,
+			Paths:    monitor.Paths,
+			Interval: interval,
+		}
+	}
+	return targets, nil
+}
+
+type CertFilesMonitor struct {
+	Label string
+	// Paths are the shell patterns of the certificate files.
+	Paths []string
+
+	Logger *log.Logger
+
+	Metrics *CertFilesCollector
+}
+
+func (c *CertFilesMonitor) ID() string {
+	return c.Label
+}
+
+// CertFile holds the certificates read from a file.
+type CertFile struct {
+	Path         string
+	Certificates []*x509.Certificate
+}
+
+func (c *CertFilesMonitor) Run(_ context.Context) error {
+	files, err := c.readCertFiles()
+
+	c.pushToPrometheus(files)
+
+	if err != nil {
+		return errors.Wrap(err, "error reading certificate files")
+	}
+	return nil
+}
+
+// readCertFiles reads the certificates of all the files matching the
+// patterns. Files that can't be read are skipped and reported in the
+// returned error.
+func (c *CertFilesMonitor) readCertFiles() ([]CertFile, error) {
+	var (
+		files    []CertFile
+		failures []string
+	)
+	for _, pattern := range c.Paths {
+		paths, err := filepath.Glob(pattern)
+		if err != nil {
+			failures = append(failures, err.Error())
+			continue
+		}
+		if len(paths) == 0 {
+			failures = append(failures, "no file matches "+pattern)
+			continue
+		}
+		for _, path := range paths {
+			certs, err := readCertFile(path)
+			if err != nil {
+				failures = append(failures, err.Error())
+				continue
+			}
+			files = append(files, CertFile{Path: path, Certificates: certs})
+		}
+	}
+
+	if len(failures) > 0 {
+		return files, errors.New(strings.Join(failures, "; "))
+	}
+	return files, nil
+}
+
+// readCertFile reads the certificates of a PEM or DER file, or of a Traefik
+// acme.json file.
+func readCertFile(path string) ([]*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading %s", path)
+	}
+
+	var certs []*x509.Certificate
+	switch {
+	case json.Valid(data):
+		certs, err = parseAcmeJSON(data)
+	case bytes.Contains(data, []byte("-----BEGIN")):
+		certs, err = parsePEMCertificates(data)
+	default:
+		certs, err = x509.ParseCertificates(data)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "error parsing %s", path)
+	}
+	if len(certs) == 0 {
+		return nil, errors.Errorf("no certificate found in %s", path)
+	}
+	return certs, nil
+}
+
+// parsePEMCertificates parses the CERTIFICATE blocks of PEM data. Other
+// blocks (e.g. private keys) are ignored.
+func parsePEMCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return certs, nil
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+}
+
+// acmeCertificates is the part of a Traefik ACME resolver storing the
+// certificates. Traefik v1 stores them at the root of the file, later
+// versions under the name of each resolver. JSON field names are matched
+// case insensitively, which covers the capitalized v1 names.
+type acmeCertificates struct {
+	Certificates []struct {
+		// Certificate is the base64 encoded PEM bundle of the certificate.
+		Certificate string `json:"certificate"`
+	} `json:"certificates"`
+}
+
+// parseAcmeJSON parses the certificates of a Traefik acme.json file.
+func parseAcmeJSON(data []byte) ([]*x509.Certificate, error) {
+	var resolvers map[string]json.RawMessage
+	if err := json.Unmarshal(data, &resolvers); err != nil {
+		return nil, errors.Wrap(err, "invalid acme.json")
+	}
+
+	stores := []json.RawMessage{data}
+	for _, resolver := range resolvers {
+		stores = append(stores, resolver)
+	}
+
+	var certs []*x509.Certificate
+	for _, store := range stores {
+		var acme acmeCertificates
+		if err := json.Unmarshal(store, &acme); err != nil {
+			// Not a resolver (e.g. the v1 Account entry).
+			continue
+		}
+		for _, c := range acme.Certificates {
+			bundle, err := base64.StdEncoding.DecodeString(c.Certificate)
+			if err != nil {
+				return nil, errors.Wrap(err, "invalid acme.json certificate encoding")
+			}
+			parsed, err := parsePEMCertificates(bundle)
+			if err != nil {
+				return nil, err
+			}
+			certs = append(certs, parsed...)
+		}
+	}
+	return certs, nil
+}
+
+func (c *CertFilesMonitor) pushToPrometheus(files []CertFile) {
+	labels := prometheus.Labels{"cert_files_monitor_name": c.Label}
+
+	// Files and certificates come and go (e.g. renewals), the series of the
+	// previous run are replaced.
+	c.Metrics.ExpiresTime.DeletePartialMatch(labels)
+	c.Metrics.CertNotAfter.DeletePartialMatch(labels)
+	c.Metrics.CertNotBefore.DeletePartialMatch(labels)
+
+	for _, file := range files {
+		fileLabels := prometheus.Labels{"cert_files_monitor_name": c.Label, "cert_file_path": file.Path}
+		for _, cert := range file.Certificates {
+			remainingTime := time.Until(cert.NotAfter).Seconds()
+			c.Logger.Printf("Certificate %s in %s for monitor %s expires in %f seconds", cert.Subject.CommonName, file.Path, c.Label, remainingTime)
+
+			values := certificateLabelValues(cert)
+			c.Metrics.ExpiresTime.MustCurryWith(fileLabels).WithLabelValues(values...).Set(remainingTime)
+			c.Metrics.CertNotAfter.MustCurryWith(fileLabels).WithLabelValues(values...).Set(float64(cert.NotAfter.Unix()))
+			c.Metrics.CertNotBefore.MustCurryWith(fileLabels).WithLabelValues(values...).Set(float64(cert.NotBefore.Unix()))
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package monitors
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/pkg/errors"
+)
+
+// defaultDockerSnapshotMaxAge is the longest age after which the snapshot of
+// the containers is refreshed. The monitors scheduled at the same time share a
+// single list call.
+const defaultDockerSnapshotMaxAge = 5 * time.Second
+
+// newEnvDockerClient creates a Docker client configured from the environment.
+func newEnvDockerClient() (DockerClient, error) {
+	return client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+}
+
+// CachedDockerClient is a DockerClient shared by the Docker monitors. The
+// underlying client is created on first use. The list of all the containers
+// and the container inspections are cached, so the monitors of a cycle make a
+// single list call and a single inspect call per container. The stats calls
+// are not cached.
+type CachedDockerClient struct {
+	// MaxAge is the longest age after which the containers are listed and
+	// inspected again. The snapshot is also refreshed after half the shortest
+	// interval of the monitors using the client, see useInterval.
+	MaxAge time.Duration
+	// minInterval is the shortest interval of the monitors, 0 when unknown.
+	minInterval atomic.Int64
+
+	newClient func() (DockerClient, error)
+	once      sync.Once
+	client    DockerClient
+	clientErr error
+
+	mu         sync.Mutex
+	containers []container.Summary
+	listedAt   time.Time
+
+	inspectMu   sync.Mutex
+	inspections map[string]cachedDockerInspection
+}
+
+// cachedDockerInspection is a container inspection and its time.
+type cachedDockerInspection struct {
+	response    container.InspectResponse
+	inspectedAt time.Time
+}
+
+// NewCachedDockerClient creates a shared client using newClient to create the
+// underlying client.
+func NewCachedDockerClient(newClient func() (DockerClient, error)) *CachedDockerClient {
+	return &CachedDockerClient{
+		MaxAge:    defaultDockerSnapshotMaxAge,
+		newClient: newClient,
+	}
+}
+
+// useInterval records the interval of a monitor using the client. Keeping the
+// snapshot for at most half the shortest interval, each check of a monitor
+// lists and inspects the containers again, while the monitors scheduled at the
+// same time still share the calls. The interval of a removed monitor is kept
+// until the client is replaced, which only refreshes the snapshot more often.
+func (c *CachedDockerClient) useInterval(interval time.Duration) {
+	for {
+		current := c.minInterval.Load()
+		if interval <= 0 || (current != 0 && current <= int64(interval)) {
+			return
+		}
+		if c.minInterval.CompareAndSwap(current, int64(interval)) {
+			return
+		}
+	}
+}
+
+// maxAge returns the age after which the snapshot is refreshed.
+func (c *CachedDockerClient) maxAge() time.Duration {
+	if interval := time.Duration(c.minInterval.Load()); interval > 0 {
+		return min(c.MaxAge, interval/2)
+	}
+	return c.MaxAge
+}
+
+// errDockerClientClosed is returned by a client closed before its first use.
+var errDockerClientClosed = errors.New("Docker client closed")
+
+// Close closes the idle connections of the underlying client. A client not
+// created yet is not created anymore.
+func (c *CachedDockerClient) Close() error {
+	c.once.Do(func() {
+		c.clientErr = errDockerClientClosed
+	})
+	if closer, ok := c.client.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+func (c *CachedDockerClient) getClient() (DockerClient, error) {
+	c.once.Do(func() {
+		c.client, c.clientErr = c.newClient()
+		if c.clientErr != nil {
+			c.clientErr = errors.Wrap(c.clientErr, "Docker client not available")
+		}
+	})
+	return c.client, c.clientErr
+}
+
+// ContainerList implements DockerClient. Listing all the containers without
+// filters returns the cached snapshot, which must not be modified.
+func (c *CachedDockerClient) ContainerList(ctx context.Context, options container.ListOptions) ([]container.Summary, error) {
+	cli, err := c.getClient()
+	if err != nil {
+		return nil, err
+	}
+	if !options.All || options.Size || options.Latest || options.Since != "" || options.Before != "" ||
+		options.Limit != 0 || options.Filters.Len() > 0 {
+		return cli.ContainerList(ctx, options)
+	}
+
+	// Concurrent callers wait for the pending list call and share its result.
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.containers != nil && time.Since(c.listedAt) < c.maxAge() {
+		return c.containers, nil
+	}
+
+	containers, err := cli.ContainerList(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+	if containers == nil {
+		containers = []container.Summary{}
+	}
+	c.containers, c.listedAt = containers, time.Now()
+	return containers, nil
+}
+
+// ContainerInspect implements DockerClient. The inspections are cached by
+// container ID, the cached responses must not be modified.
+func (c *CachedDockerClient) ContainerInspect(ctx context.Context, containerID string) (container.InspectResponse, error) {
+	cli, err := c.getClient()
+	if err != nil {
+		return container.InspectResponse{}, err
+	}
+
+	c.inspectMu.Lock()
+	cached, ok := c.inspections[containerID]
+	c.inspectMu.Unlock()
+	if ok && time.Since(cached.inspectedAt) < c.maxAge() {
+		return cached.response, nil
+	}
+
+	response, err := cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return response, err
+	}
+
+	c.inspectMu.Lock()
+	defer c.inspectMu.Unlock()
+	if c.inspections == nil {
+		c.inspections = make(map[string]cachedDockerInspection)
+	}
+	// Drop the expired inspections, including those of the removed containers.
+	maxAge := c.maxAge()
+	for id, inspection := range c.inspections {
+		if time.Since(inspection.inspectedAt) >= maxAge {
+			delete(c.inspections, id)
+		}
+	}
+	c.inspections[containerID] = cachedDockerInspection{response: response, inspectedAt: time.Now()}
+	return response, nil
+}
+
+// ContainerStatsOneShot implements DockerClient.
+func (c *CachedDockerClient) ContainerStatsOneShot(ctx context.Context, containerID string) (container.StatsResponseReader, error) {
+	cli, err := c.getClient()
+	if err != nil {
+		return container.StatsResponseReader{}, err
+	}
+	return cli.ContainerStatsOneShot(ctx, containerID)
+}
@@ -0,0 +1,241 @@
+package monitors
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+)
+
+// countingDockerClient counts the list and inspect calls of the mock client.
+type countingDockerClient struct {
+	mockDockerClient
+	lists    atomic.Int32
+	inspects atomic.Int32
+}
+
+func (c *countingDockerClient) ContainerList(ctx context.Context, options container.ListOptions) ([]container.Summary, error) {
+	c.lists.Add(1)
+	return c.mockDockerClient.ContainerList(ctx, options)
+}
+
+func TestCachedDockerClient_ContainerList(t *testing.T) {
+	mock := &countingDockerClient{mockDockerClient: mockDockerClient{
+		containers: []container.Summary{{ID: "abc", Names: []string{"/nginx"}, State: "running"}},
+	}}
+	cached := NewCachedDockerClient(func() (DockerClient, error) { return mock, nil })
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			containers, err := cached.ContainerList(t.Context(), container.ListOptions{All: true})
+			if err != nil || len(containers) != 1 {
+				t.Errorf("ContainerList() = %v, %v", containers, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if lists := mock.lists.Load(); lists != 1 {
+		t.Errorf("Expected 1 list call, got %d", lists)
+	}
+
+	// Other list options are not cached.
+	options := container.ListOptions{All: true, Filters: filters.NewArgs(filters.Arg("status", "running"))}
+	if _, err := cached.ContainerList(t.Context(), options); err != nil {
+		t.Fatalf("ContainerList() error = %v", err)
+	}
+	if lists := mock.lists.Load(); lists != 2 {
+		t.Errorf("Expected 2 list calls, got %d", lists)
+	}
+
+	// The snapshot is refreshed once expired.
+	cached.MaxAge = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	if _, err := cached.ContainerList(t.Context(), container.ListOptions{All: true}); err != nil {
+		t.Fatalf("ContainerList() error = %v", err)
+	}
+	if lists := mock.lists.Load(); lists != 3 {
+		t.Errorf("Expected 3 list calls, got %d", lists)
+	}
+}
+
+func (c *countingDockerClient) ContainerInspect(ctx context.Context, containerID string) (container.InspectResponse, error) {
+	c.inspects.Add(1)
+	return c.mockDockerClient.ContainerInspect(ctx, containerID)
+}
+
+func TestCachedDockerClient_ContainerInspect(t *testing.T) {
+	mock := &countingDockerClient{mockDockerClient: mockDockerClient{
+		inspections: map[string]container.InspectResponse{
+			"abc": newInspectResponse(&container.State{Status: "running", Running: true}),
+		},
+	}}
+	cached := NewCachedDockerClient(func() (DockerClient, error) { return mock, nil })
+
+	for _, id := range []string{"abc", "abc", "def"} {
+		if _, err := cached.ContainerInspect(t.Context(), id); err != nil {
+			t.Fatalf("ContainerInspect() error = %v", err)
+		}
+	}
+	if inspects := mock.inspects.Load(); inspects != 2 {
+		t.Errorf("Expected 2 inspect calls, got %d", inspects)
+	}
+
+	// Errors are not cached.
+	mock.inspectErr = errors.New("daemon unavailable")
+	for range 2 {
+		if _, err := cached.ContainerInspect(t.Context(), "ghi"); err == nil {
+			t.Error("ContainerInspect() should return the inspect error")
+		}
+	}
+	if inspects := mock.inspects.Load(); inspects != 4 {
+		t.Errorf("Expected 4 inspect calls, got %d", inspects)
+	}
+
+	// The inspections are refreshed once expired.
+	mock.inspectErr = nil
+	cached.MaxAge = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	if _, err := cached.ContainerInspect(t.Context(), "abc"); err != nil {
+		t.Fatalf("ContainerInspect() error = %v", err)
+	}
+	if inspects := mock.inspects.Load(); inspects != 5 {
+		t.Errorf("Expected 5 inspect calls, got %d", inspects)
+	}
+	if len(cached.inspections) != 1 {
+		t.Errorf("Expected the expired inspections to be dropped, got %d", len(cached.inspections))
+	}
+}
+
+func TestCachedDockerClient_ListError(t *testing.T) {
+	mock := &countingDockerClient{mockDockerClient: mockDockerClient{err: errors.New("daemon unavailable")}}
+	cached := NewCachedDockerClient(func() (DockerClient, error) { return mock, nil })
+
+	for range 2 {
+		if _, err := cached.ContainerList(t.Context(), container.ListOptions{All: true}); err == nil {
+			t.Error("ContainerList() should return the list error")
+		}
+	}
+	// Errors are not cached.
+	if lists := mock.lists.Load(); lists != 2 {
+		t.Errorf("Expected 2 list calls, got %d", lists)
+	}
+}
+
+func TestCachedDockerClient_NewClientError(t *testing.T) {
+	calls := 0
+	cached := NewCachedDockerClient(func() (DockerClient, error) {
+		calls++
+		return nil, errors.New("invalid DOCKER_HOST")
+	})
+
+	if _, err := cached.ContainerList(t.Context(), container.ListOptions{All: true}); err == nil {
+		t.Error("ContainerList() should fail without client")
+	}
+	if _, err := cached.ContainerInspect(t.Context(), "abc"); err == nil {
+		t.Error("ContainerInspect() should fail without client")
+	}
+	if calls != 1 {
+		t.Errorf("Expected the client to be created once, got %d", calls)
+	}
+}
+
+func TestCachedDockerClient_Close(t *testing.T) {
+	calls := 0
+	cached := NewCachedDockerClient(func() (DockerClient, error) {
+		calls++
+		return &closingDockerClient{}, nil
+	})
+
+	// A client closed before its first use is not created.
+	if err := cached.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, err := cached.ContainerInspect(t.Context(), "abc"); err == nil {
+		t.Error("ContainerInspect() should fail once closed")
+	}
+	if calls != 0 {
+		t.Errorf("Expected no client, got %d", calls)
+	}
+
+	mock := &closingDockerClient{}
+	cached = NewCachedDockerClient(func() (DockerClient, error) { return mock, nil })
+	if _, err := cached.ContainerList(t.Context(), container.ListOptions{All: true}); err != nil {
+		t.Fatalf("ContainerList() error = %v", err)
+	}
+	if err := cached.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if !mock.closed {
+		t.Error("Expected the underlying client to be closed")
+	}
+}
+
+func TestDockerMonitorFactory_SharedClient(t *testing.T) {
+	mock := &countingDockerClient{mockDockerClient: mockDockerClient{
+		containers: []container.Summary{
+			{ID: "1", Names: []string{"/nginx"}, State: "running"},
+			{ID: "2", Names: []string{"/redis"}, State: "running"},
+		},
+	}}
+	factory := DockerMonitorFactory{Client: NewCachedDockerClient(func() (DockerClient, error) { return mock, nil })}
+	collector := factory.CreateCollector()
+
+	for i, name := range []string{"nginx", "redis", "nginx"} {
+		target := DockerTarget{Name: name + strconv.Itoa(i), ContainerName: name}
+		monitor := factory.CreateMonitor(target, collector, log.Default())
+		if err := monitor.Run(t.Context()); err != nil {
+			t.Fatalf("Run() returned error: %v", err)
+		}
+	}
+
+	if lists := mock.lists.Load(); lists != 1 {
+		t.Errorf("Expected 1 list call for all the monitors, got %d", lists)
+	}
+	if inspects := mock.inspects.Load(); inspects != 2 {
+		t.Errorf("Expected 1 inspect call per container, got %d", inspects)
+	}
+}
+
+func TestCachedDockerClient_useInterval(t *testing.T) {
+	cached := NewCachedDockerClient(func() (DockerClient, error) { return &mockDockerClient{}, nil })
+	if got := cached.maxAge(); got != defaultDockerSnapshotMaxAge {
+		t.Errorf("Expected max age %v without monitors, got %v", defaultDockerSnapshotMaxAge, got)
+	}
+
+	tests := []struct {
+		interval time.Duration
+		expected time.Duration
+	}{
+		{interval: 60 * time.Second, expected: defaultDockerSnapshotMaxAge},
+		{interval: 4 * time.Second, expected: 2 * time.Second},
+		{interval: 30 * time.Second, expected: 2 * time.Second},
+		{interval: time.Second, expected: 500 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		cached.useInterval(tt.interval)
+		if got := cached.maxAge(); got != tt.expected {
+			t.Errorf("After a %v interval: expected max age %v, got %v", tt.interval, tt.expected, got)
+		}
+	}
+}
+
+func TestDockerMonitorFactory_CreateMonitor_Interval(t *testing.T) {
+	cached := NewCachedDockerClient(func() (DockerClient, error) { return &mockDockerClient{}, nil })
+	factory := DockerMonitorFactory{Client: cached}
+
+	factory.CreateMonitor(DockerTarget{Name: "nginx", ContainerName: "nginx", Interval: 2}, factory.CreateCollector(), log.Default())
+
+	if got := cached.maxAge(); got != time.Second {
+		t.Errorf("Expected the max age to follow the monitor interval, got %v", got)
+	}
+}
@@ -0,0 +1,160 @@
+package monitors
+
+import (
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"aireone.xyz/labtime/internal/yamlconfig"
+	"github.com/docker/docker/client"
+	"github.com/pkg/errors"
+)
+
+// Schemes of the Docker host URLs.
+var dockerHostSchemes = []string{"tcp", "ssh", "unix"}
+
+// Idle connection limits of the default transport of the Docker client,
+// preventing long-lived clients from leaking connections (moby/moby#45539).
+const (
+	dockerMaxIdleConns    = 6
+	dockerIdleConnTimeout = 30 * time.Second
+)
+
+// DockerHost is a Docker daemon from the docker_hosts section.
+type DockerHost struct {
+	// Name is exported as the docker_host label.
+	Name string
+	URL  string
+	// Client is shared by the monitors of the host.
+	Client DockerClient
+
+	// tls holds the TLS settings the client was created with.
+	tls *yamlconfig.HTTPTLSDTO
+}
+
+// sameSettings reports whether both hosts connect to the daemon the same way.
+func (h *DockerHost) sameSettings(other *DockerHost) bool {
+	if h.URL != other.URL || (h.tls == nil) != (other.tls == nil) {
+		return false
+	}
+	return h.tls == nil || *h.tls == *other.tls
+}
+
+// DockerHosts keeps the Docker hosts across the configuration reloads, so the
+// unchanged hosts keep their client and its connections.
+type DockerHosts struct {
+	mu    sync.Mutex
+	hosts map[string]*DockerHost
+}
+
+// NewDockerHosts creates an empty set of Docker hosts.
+func NewDockerHosts() *DockerHosts {
+	return &DockerHosts{}
+}
+
+// update replaces the hosts with those of a new configuration and returns the
+// hosts to use. The unchanged hosts are kept, and the clients of the replaced
+// and removed hosts are closed.
+func (h *DockerHosts) update(hosts map[string]*DockerHost) map[string]*DockerHost {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for name, host := range hosts {
+		if previous, ok := h.hosts[name]; ok && previous.sameSettings(host) {
+			hosts[name] = previous
+			delete(h.hosts, name)
+		}
+	}
+	for _, previous := range h.hosts {
+		if closer, ok := previous.Client.(io.Closer); ok {
+			_ = closer.Close()
+		}
+	}
+	h.hosts = hosts
+	return hosts
+}
+
+// newDockerHostClient creates a Docker client connected to the daemon at
+// host. The TLS configuration, if any, is used to connect to a tcp:// daemon.
+func newDockerHostClient(host string, tlsConfig *tls.Config) (DockerClient, error) {
+	opts := []client.Opt{client.WithAPIVersionNegotiation()}
+
+	switch {
+	case strings.HasPrefix(host, "ssh://"):
+		sshHost, err := parseDockerSSHHost(host)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid Docker host %q", host)
+		}
+		// The ssh connections run "docker system dial-stdio" on the remote host.
+		opts = append(opts, client.WithHost(dockerSSHDaemonHost), client.WithDialContext(sshHost.dial))
+	case tlsConfig != nil:
+		// The client switches to https when its transport has a TLS
+		// configuration. The transport must be set before the host.
+		transport := &http.Transport{
+			TLSClientConfig: tlsConfig,
+			MaxIdleConns:    dockerMaxIdleConns,
+			IdleConnTimeout: dockerIdleConnTimeout,
+		}
+		httpClient := &http.Client{Transport: transport, CheckRedirect: client.CheckRedirect}
+		opts = append(opts, client.WithHTTPClient(httpClient), client.WithHost(host))
+	default:
+		opts = append(opts, client.WithHost(host))
+	}
+
+	cli, err := client.NewClientWithOpts(opts...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error creating Docker client for %q", host)
+	}
+	return cli, nil
+}
+
+// dockerHosts creates the Docker hosts of the configuration by name. The
+// clients are created on first use.
+func dockerHosts(config *yamlconfig.YamlConfig) (map[string]*DockerHost, error) {
+	hosts := make(map[string]*DockerHost, len(config.DockerHosts))
+	for i, dto := range config.DockerHosts {
+		if dto.Name == "" {
+			return nil, errors.Errorf("no name for docker host %d", i)
+		}
+		if _, ok := hosts[dto.Name]; ok {
+			return nil, errors.Errorf("duplicate docker host '%s'", dto.Name)
+		}
+
+		hostURL, err := url.Parse(dto.Host)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid host for docker host '%s'", dto.Name)
+		}
+		if !slices.Contains(dockerHostSchemes, hostURL.Scheme) {
+			return nil, errors.Errorf("invalid host %q for docker host '%s', the scheme must be one of %s",
+				dto.Host, dto.Name, strings.Join(dockerHostSchemes, ", "))
+		}
+		if dto.TLS != nil && hostURL.Scheme != "tcp" {
+			return nil, errors.Errorf("tls requires a tcp:// host for docker host '%s'", dto.Name)
+		}
+		if hostURL.Scheme == "ssh" {
+			if _, err := parseDockerSSHHost(dto.Host); err != nil {
+				return nil, errors.Wrapf(err, "invalid host for docker host '%s'", dto.Name)
+			}
+		}
+		tlsConfig, err := newHTTPTLSConfig(dto.TLS)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid tls for docker host '%s'", dto.Name)
+		}
+
+		host := dto.Host
+		hosts[dto.Name] = &DockerHost{
+			Name: dto.Name,
+			URL:  host,
+			Client: NewCachedDockerClient(func() (DockerClient, error) {
+				return newDockerHostClient(host, tlsConfig)
+			}),
+			tls: dto.TLS,
+		}
+	}
+	return hosts, nil
+}
@@ -1,21 +1,39 @@
 package monitors
 
 import (
+	"cmp"
 	"context"
 	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
 
 	"aireone.xyz/labtime/internal/yamlconfig"
+	cerrdefs "github.com/containerd/errdefs"
 	"github.com/docker/docker/api/types/container"
-	"github.com/docker/docker/client"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
 // DockerTarget represents a Docker container monitoring target.
 type DockerTarget struct {
-	Name          string `yaml:"name"`
+	Name string `yaml:"name"`
+	// Host is the Docker daemon running the containers, nil for the local
+	// daemon.
+	Host          *DockerHost
 	ContainerName string `yaml:"container_name"`
-	Interval      int    `yaml:"interval,omitempty"`
+	// Labels, including the compose project and service labels, and
+	// NamePattern select the containers along with ContainerName.
+	Labels           map[string]string
+	NamePattern      *regexp.Regexp
+	ExpectedReplicas int `yaml:"expected_replicas,omitempty"`
+	// CrashLoopRestarts restarts within CrashLoopWindow seconds flag a crash
+	// loop.
+	CrashLoopRestarts int  `yaml:"crash_loop_restarts,omitempty"`
+	CrashLoopWindow   int  `yaml:"crash_loop_window,omitempty"`
+	Stats             bool `yaml:"stats,omitempty"`
+	Interval          int  `yaml:"interval,omitempty"`
 }
 
 // GetName implements the Target interface.
@@ -29,53 +47,277 @@ func (d DockerTarget) GetInterval() int {
 }
 
 // DockerMonitorFactory implements MonitorFactory for Docker monitoring.
-type DockerMonitorFactory struct{}
+type DockerMonitorFactory struct {
+	// Client is shared by the monitors of the local Docker daemon. When nil,
+	// each monitor creates its own client. The monitors of a remote host use
+	// the client of the host.
+	Client DockerClient
+}
+
+// NewDockerMonitorFactory creates a factory whose monitors share a Docker
+// client and a snapshot of the containers.
+func NewDockerMonitorFactory() DockerMonitorFactory {
+	return DockerMonitorFactory{
+		Client: NewCachedDockerClient(newEnvDockerClient),
+	}
+}
+
+// Health states reported by the labtime_docker_container_health metric.
+var dockerHealthStates = []container.HealthStatus{
+	container.Healthy,
+	container.Unhealthy,
+	container.Starting,
+	container.NoHealthcheck,
+}
+
+// DockerCollector groups the Prometheus metrics exported by the Docker
+// monitors.
+type DockerCollector struct {
+	Status         *prometheus.GaugeVec
+	Health         *prometheus.GaugeVec
+	HealthExitCode *prometheus.GaugeVec
+	RestartCount   *prometheus.GaugeVec
+	ExitCode       *prometheus.GaugeVec
+	OOMKilled      *prometheus.GaugeVec
+	Uptime         *prometheus.GaugeVec
+	CrashLoop      *prometheus.GaugeVec
+	CPU            *prometheus.GaugeVec
+	MemoryUsage    *prometheus.GaugeVec
+	MemoryLimit    *prometheus.GaugeVec
+	// The network and block IO bytes are totals kept by Docker, exported as
+	// counters.
+	NetworkRx    *dockerCounterVec
+	NetworkTx    *dockerCounterVec
+	BlockIORead  *dockerCounterVec
+	BlockIOWrite *dockerCounterVec
+	// ExpectedReplicas and RunningReplicas are exported per target.
+	ExpectedReplicas *prometheus.GaugeVec
+	RunningReplicas  *prometheus.GaugeVec
+
+	multiCollector
+}
+
+// dockerMetricVec is a collector of the metrics exported per container.
+type dockerMetricVec interface {
+	prometheus.Collector
+	Delete(labels prometheus.Labels) bool
+	DeletePartialMatch(labels prometheus.Labels) int
+}
+
+// containerCollectors returns the collectors of the metrics exported per
+// container.
+func (c *DockerCollector) containerCollectors() []dockerMetricVec {
+	collectors := []dockerMetricVec{c.Status, c.Health, c.HealthExitCode}
+	collectors = append(collectors, c.stateCollectors()...)
+	return append(collectors, c.statsCollectors()...)
+}
+
+// stateCollectors returns the collectors of the metrics read from the
+// container inspection.
+func (c *DockerCollector) stateCollectors() []dockerMetricVec {
+	return []dockerMetricVec{c.RestartCount, c.ExitCode, c.OOMKilled, c.Uptime, c.CrashLoop}
+}
+
+// statsCollectors returns the collectors of the metrics read from the
+// container stats.
+func (c *DockerCollector) statsCollectors() []dockerMetricVec {
+	return []dockerMetricVec{c.CPU, c.MemoryUsage, c.MemoryLimit, c.NetworkRx, c.NetworkTx, c.BlockIORead, c.BlockIOWrite}
+}
 
-// CreateCollector creates a Prometheus GaugeVec for Docker monitoring.
-func (d DockerMonitorFactory) CreateCollector() *prometheus.GaugeVec {
-	return prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "labtime_docker_container_status",
-		Help: "The status of the Docker container (1 = running, 0 = not running).",
-	}, []string{"docker_monitor_name", "container_name"})
+// CreateCollector creates the Prometheus collectors for Docker monitoring.
+func (d DockerMonitorFactory) CreateCollector() *DockerCollector {
+	labels := []string{"docker_monitor_name", "docker_host", "container_name"}
+	c := &DockerCollector{
+		Status: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "labtime_docker_container_status",
+			Help: "The status of the Docker container (1 = running, 0 = not running).",
+		}, labels),
+		Health: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "labtime_docker_container_health",
+			Help: "The health check status of the Docker container (1 for the current state among healthy, unhealthy, starting and none, 0 for the others).",
+		}, append(labels, "state")),
+		HealthExitCode: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "labtime_docker_container_health_exit_code",
+			Help: "The exit code of the last health check of the Docker container.",
+		}, labels),
+		RestartCount: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "labtime_docker_container_restart_count",
+			Help: "The number of times the Docker container has been restarted by Docker.",
+		}, labels),
+		ExitCode: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "labtime_docker_container_exit_code",
+			Help: "The exit code of the last run of the Docker container.",
+		}, labels),
+		OOMKilled: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "labtime_docker_container_oom_killed",
+			Help: "Whether the last run of the Docker container was killed because it ran out of memory (1 = killed, 0 = not killed).",
+		}, labels),
+		Uptime: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "labtime_docker_container_uptime_seconds",
+			Help: "The duration (in seconds) since the Docker container started, 0 when it is not running.",
+		}, labels),
+		CrashLoop: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "labtime_docker_container_crash_loop",
+			Help: "Whether the Docker container restarted repeatedly within the crash loop window (1 = crash looping, 0 = stable).",
+		}, labels),
+		CPU: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "labtime_docker_container_cpu_percent",
+			Help: "The CPU usage (in percent, 100 being one full core) of the Docker container since the previous check.",
+		}, labels),
+		MemoryUsage: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "labtime_docker_container_memory_usage_bytes",
+			Help: "The memory used by the Docker container, without the page cache.",
+		}, labels),
+		MemoryLimit: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "labtime_docker_container_memory_limit_bytes",
+			Help: "The memory limit of the Docker container.",
+		}, labels),
+		NetworkRx: newDockerCounterVec(
+			"labtime_docker_container_network_receive_bytes_total",
+			"The bytes received by the Docker container on all its networks.",
+			labels),
+		NetworkTx: newDockerCounterVec(
+			"labtime_docker_container_network_transmit_bytes_total",
+			"The bytes sent by the Docker container on all its networks.",
+			labels),
+		BlockIORead: newDockerCounterVec(
+			"labtime_docker_container_block_io_read_bytes_total",
+			"The bytes read by the Docker container from block devices.",
+			labels),
+		BlockIOWrite: newDockerCounterVec(
+			"labtime_docker_container_block_io_write_bytes_total",
+			"The bytes written by the Docker container to block devices.",
+			labels),
+		ExpectedReplicas: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "labtime_docker_expected_replicas",
+			Help: "The number of running containers expected for the Docker monitor.",
+		}, []string{"docker_monitor_name", "docker_host"}),
+		RunningReplicas: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "labtime_docker_running_replicas",
+			Help: "The number of running containers selected by the Docker monitor.",
+		}, []string{"docker_monitor_name", "docker_host"}),
+	}
+	c.multiCollector = multiCollector{c.ExpectedReplicas, c.RunningReplicas}
+	for _, collector := range c.containerCollectors() {
+		c.multiCollector = append(c.multiCollector, collector)
+	}
+	return c
 }
 
 // CreateMonitor creates a Docker monitor instance.
-func (d DockerMonitorFactory) CreateMonitor(target DockerTarget, collector *prometheus.GaugeVec, logger *log.Logger) Job {
-	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
-	if err != nil {
-		logger.Printf("Failed to create Docker client for monitor '%s': %v", target.Name, err)
-		// Return a monitor with nil client - it will fail gracefully during Run()
-		cli = nil
+func (d DockerMonitorFactory) CreateMonitor(target DockerTarget, collector *DockerCollector, logger *log.Logger) Job {
+	var host string
+	cli := d.Client
+	if target.Host != nil {
+		host, cli = target.Host.Name, target.Host.Client
+	} else if cli == nil {
+		// The client is created on first use, errors are reported by Run().
+		cli = NewCachedDockerClient(newEnvDockerClient)
+	}
+	if cached, ok := cli.(*CachedDockerClient); ok {
+		cached.useInterval(time.Duration(target.Interval) * time.Second)
 	}
 
 	return &DockerMonitor{
-		Label:                  target.Name,
-		ContainerName:          target.ContainerName,
-		Logger:                 logger,
-		ContainerStatusMonitor: collector,
-		client:                 cli,
+		Label:             target.Name,
+		Host:              host,
+		ContainerName:     target.ContainerName,
+		Labels:            target.Labels,
+		NamePattern:       target.NamePattern,
+		ExpectedReplicas:  target.ExpectedReplicas,
+		CrashLoopRestarts: target.CrashLoopRestarts,
+		CrashLoopWindow:   time.Duration(target.CrashLoopWindow) * time.Second,
+		Stats:             target.Stats,
+		Logger:            logger,
+		Metrics:           collector,
+		client:            cli,
 	}
 }
 
 // DockerTargetProvider implements TargetProvider for Docker targets.
-type DockerTargetProvider struct{}
+type DockerTargetProvider struct {
+	// Hosts keeps the Docker hosts across the configuration reloads. When nil,
+	// the hosts are created again on each call.
+	Hosts *DockerHosts
+}
 
 // GetTargets extracts Docker targets from the configuration.
 func (d DockerTargetProvider) GetTargets(config *yamlconfig.YamlConfig) ([]DockerTarget, error) {
+	hosts, err := dockerHosts(config)
+	if err != nil {
+		return nil, err
+	}
+
 	targets := make([]DockerTarget, len(config.DockerMonitors))
 	for i, monitor := range config.DockerMonitors {
 		name := monitor.Name
 		if name == "" {
-			name = monitor.ContainerName
+			name = cmp.Or(monitor.ContainerName, monitor.ComposeService, monitor.ComposeProject, monitor.NamePattern)
+		}
+		if name == "" {
+			return nil, errors.Errorf("no name for docker target %d selecting containers by labels", i)
+		}
+		var host *DockerHost
+		if monitor.Host != "" {
+			var ok bool
+			host, ok = hosts[monitor.Host]
+			if !ok {
+				return nil, errors.Errorf("unknown docker host '%s' for target '%s'", monitor.Host, name)
+			}
+		}
+		labels, err := dockerSelectorLabels(monitor)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid selector for target '%s'", name)
+		}
+		var namePattern *regexp.Regexp
+		if monitor.NamePattern != "" {
+			namePattern, err = regexp.Compile(monitor.NamePattern)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid name_pattern for target '%s'", name)
+			}
+		}
+		if monitor.ContainerName == "" && len(labels) == 0 && namePattern == nil {
+			return nil, errors.Errorf("no container selector for target '%s'", name)
+		}
+		if monitor.ExpectedReplicas < 0 {
+			return nil, errors.Errorf("invalid expected_replicas %d for target '%s'", monitor.ExpectedReplicas, name)
 		}
 		interval := monitor.Interval
 		if interval == 0 {
 			interval = 60
 		}
+		crashLoopRestarts := monitor.CrashLoopRestarts
+		if crashLoopRestarts == 0 {
+			crashLoopRestarts = defaultCrashLoopRestarts
+		} else if crashLoopRestarts < 0 {
+			return nil, errors.Errorf("invalid crash_loop_restarts %d for target '%s'", crashLoopRestarts, name)
+		}
+		crashLoopWindow := monitor.CrashLoopWindow
+		if crashLoopWindow == 0 {
+			crashLoopWindow = int(defaultCrashLoopWindow.Seconds())
+		} else if crashLoopWindow < 0 {
+			return nil, errors.Errorf("invalid crash_loop_window %d for target '%s'", crashLoopWindow, name)
+		}
 		targets[i] = DockerTarget{
-			Name:          name,
-			ContainerName: monitor.ContainerName,
-			Interval:      interval,
+			Name:              name,
+			Host:              host,
+			ContainerName:     monitor.ContainerName,
+			Labels:            labels,
+			NamePattern:       namePattern,
+			ExpectedReplicas:  monitor.ExpectedReplicas,
+			CrashLoopRestarts: crashLoopRestarts,
+			CrashLoopWindow:   crashLoopWindow,
+			Stats:             monitor.Stats,
+			Interval:          interval,
+		}
+	}
+
+	if d.Hosts != nil {
+		hosts = d.Hosts.update(hosts)
+		for i := range targets {
+			if targets[i].Host != nil {
+				targets[i].Host = hosts[targets[i].Host.Name]
+			}
 		}
 	}
 	return targets, nil
@@ -84,17 +326,46 @@ func (d DockerTargetProvider) GetTargets(config *yamlconfig.YamlConfig) ([]Docke
 // DockerClient interface for testing purposes.
 type DockerClient interface {
 	ContainerList(ctx context.Context, options container.ListOptions) ([]container.Summary, error)
+	ContainerInspect(ctx context.Context, containerID string) (container.InspectResponse, error)
+	ContainerStatsOneShot(ctx context.Context, containerID string) (container.StatsResponseReader, error)
 }
 
 type DockerMonitor struct {
-	Label         string
+	Label string
+	// Host is the name of the Docker host, empty for the local daemon.
+	Host string
+	// ContainerName, Labels and NamePattern select the monitored containers.
+	// All of the set criteria must match.
 	ContainerName string
+	Labels        map[string]string
+	NamePattern   *regexp.Regexp
+	// ExpectedReplicas is the number of running containers expected, zero
+	// disables the metric.
+	ExpectedReplicas int
+	// CrashLoopRestarts restarts within the CrashLoopWindow flag a crash loop.
+	// Zero uses the defaults.
+	CrashLoopRestarts int
+	CrashLoopWindow   time.Duration
+	// Stats enables the resource usage metrics.
+	Stats bool
 
 	Logger *log.Logger
 
-	ContainerStatusMonitor *prometheus.GaugeVec
+	Metrics *DockerCollector
 
 	client DockerClient
+
+	// mu guards the history, the runs of a monitor can overlap.
+	mu sync.Mutex
+	// history holds the samples of the previous runs by container name.
+	history map[string]*dockerContainerHistory
+}
+
+// dockerContainerHistory holds the samples of a container kept between runs.
+type dockerContainerHistory struct {
+	crashLoop crashLoopDetector
+	// previousCPU is the CPU usage of the previous stats sample.
+	previousCPU *container.CPUStats
 }
 
 func (d *DockerMonitor) ID() string {
@@ -102,21 +373,79 @@ func (d *DockerMonitor) ID() string {
 }
 
 func (d *DockerMonitor) Run(ctx context.Context) error {
-	status, err := d.checkContainerStatus(ctx)
+	statuses, err := d.checkContainerStatus(ctx)
 	if err != nil {
 		return errors.Wrap(err, "error checking Docker container status")
 	}
 
-	d.pushToPrometheus(status)
+	d.forgetContainers(statuses)
+
+	running := 0
+	var failures []string
+	for _, status := range statuses {
+		if status.IsRunning {
+			running++
+		}
+		if status.Err != nil {
+			failures = append(failures, status.Name+": "+status.Err.Error())
+		}
+		d.pushToPrometheus(status)
 
+		if d.Stats {
+			var stats *DockerContainerStats
+			if status.IsRunning {
+				stats, err = d.containerStats(ctx, status)
+				if err != nil {
+					failures = append(failures, status.Name+": "+err.Error())
+				}
+			}
+			d.pushStatsToPrometheus(status.Name, stats)
+		}
+	}
+
+	d.pushReplicasToPrometheus(running)
+
+	if len(failures) > 0 {
+		return errors.Errorf("error checking Docker containers: %s", strings.Join(failures, "; "))
+	}
 	return nil
 }
 
 type DockerHealthCheckerData struct {
+	// ID is the container ID, empty when the container is not found.
+	ID string
+	// Name is the name of the container, without the leading slash.
+	Name      string
 	IsRunning bool
+	// Health is the health check status, empty when the container is not
+	// found.
+	Health container.HealthStatus
+	// HealthExitCode is the exit code of the last health check, nil when the
+	// container has not been checked yet.
+	HealthExitCode *int
+	// State is the state of the container from its inspection, nil when the
+	// container is not found.
+	State *DockerContainerState
+	// Err is the error of the container inspection. The status then only
+	// comes from the container list.
+	Err error
 }
 
-func (d *DockerMonitor) checkContainerStatus(ctx context.Context) (*DockerHealthCheckerData, error) {
+// DockerContainerState holds the details of the container state only
+// available from the container inspection.
+type DockerContainerState struct {
+	RestartCount int
+	ExitCode     int
+	OOMKilled    bool
+	// StartedAt is zero when the container never started.
+	StartedAt time.Time
+}
+
+// checkContainerStatus returns the status of the selected containers. A
+// container selected by name that doesn't exist is reported as not running.
+// The containers removed since the list are skipped, and the other inspection
+// errors are reported by the status of the container.
+func (d *DockerMonitor) checkContainerStatus(ctx context.Context) ([]*DockerHealthCheckerData, error) {
 	// Check if Docker client is available
 	if d.client == nil {
 		return nil, errors.New("Docker client not available")
@@ -128,26 +457,118 @@ func (d *DockerMonitor) checkContainerStatus(ctx context.Context) (*DockerHealth
 		return nil, errors.Wrap(err, "failed to list containers")
 	}
 
-	// Search for the target container
+	var statuses []*DockerHealthCheckerData
 	for _, c := range containers {
-		for _, name := range c.Names {
-			// Container names in Docker API start with '/'
-			containerName := name
-			if len(name) > 0 && name[0] == '/' {
-				containerName = name[1:]
-			}
+		name, ok := d.matches(c)
+		if !ok {
+			continue
+		}
 
-			if containerName == d.ContainerName {
-				isRunning := c.State == "running"
-				d.Logger.Printf("Container '%s' found with state: %s", d.ContainerName, c.State)
-				return &DockerHealthCheckerData{IsRunning: isRunning}, nil
-			}
+		isRunning := c.State == "running"
+		d.Logger.Printf("Container '%s' found with state: %s", name, c.State)
+		status, err := d.inspectContainer(ctx, c.ID, name, isRunning)
+		if cerrdefs.IsNotFound(err) {
+			// The container was removed since the containers were listed.
+			d.Logger.Printf("Container '%s' removed", name)
+			continue
+		}
+		if err != nil {
+			status = &DockerHealthCheckerData{ID: c.ID, Name: name, IsRunning: isRunning, Err: err}
 		}
+		statuses = append(statuses, status)
 	}
 
-	// Container not found
-	d.Logger.Printf("Container '%s' not found", d.ContainerName)
-	return &DockerHealthCheckerData{IsRunning: false}, nil
+	if len(statuses) == 0 && d.ContainerName != "" {
+		// Container not found
+		d.Logger.Printf("Container '%s' not found", d.ContainerName)
+		return []*DockerHealthCheckerData{{Name: d.ContainerName, IsRunning: false}}, nil
+	}
+	if len(statuses) == 0 {
+		d.Logger.Printf("No container selected by Docker monitor '%s'", d.Label)
+	}
+	return statuses, nil
+}
+
+// inspectContainer completes the container status with the details only
+// available from the container inspection.
+func (d *DockerMonitor) inspectContainer(ctx context.Context, id, name string, isRunning bool) (*DockerHealthCheckerData, error) {
+	inspect, err := d.client.ContainerInspect(ctx, id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to inspect container '%s'", name)
+	}
+
+	data := &DockerHealthCheckerData{ID: id, Name: name, IsRunning: isRunning, Health: container.NoHealthcheck}
+	if inspect.ContainerJSONBase == nil || inspect.State == nil {
+		return data, nil
+	}
+
+	data.State = &DockerContainerState{
+		RestartCount: inspect.RestartCount,
+		ExitCode:     inspect.State.ExitCode,
+		OOMKilled:    inspect.State.OOMKilled,
+	}
+	if startedAt, err := time.Parse(time.RFC3339Nano, inspect.State.StartedAt); err == nil && !startedAt.IsZero() {
+		data.State.StartedAt = startedAt
+	}
+
+	if inspect.State.Health == nil {
+		return data, nil
+	}
+
+	health := inspect.State.Health
+	data.Health = health.Status
+	if n := len(health.Log); n > 0 && health.Log[n-1] != nil {
+		exitCode := health.Log[n-1].ExitCode
+		data.HealthExitCode = &exitCode
+	}
+	return data, nil
+}
+
+// containerHistory returns the samples of the previous runs of the container.
+// It must be called with mu held.
+func (d *DockerMonitor) containerHistory(name string) *dockerContainerHistory {
+	if d.history == nil {
+		d.history = make(map[string]*dockerContainerHistory)
+	}
+	history, ok := d.history[name]
+	if !ok {
+		history = &dockerContainerHistory{
+			crashLoop: crashLoopDetector{Restarts: d.CrashLoopRestarts, Window: d.CrashLoopWindow},
+		}
+		d.history[name] = history
+	}
+	return history
+}
+
+// forgetContainers removes the series and the history of the containers that
+// are not selected anymore.
+func (d *DockerMonitor) forgetContainers(statuses []*DockerHealthCheckerData) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	selected := make(map[string]bool, len(statuses))
+	for _, status := range statuses {
+		selected[status.Name] = true
+	}
+
+	for name := range d.history {
+		if selected[name] {
+			continue
+		}
+		labels := d.labels(name)
+		for _, collector := range d.Metrics.containerCollectors() {
+			collector.DeletePartialMatch(labels)
+		}
+		delete(d.history, name)
+	}
+}
+
+func (d *DockerMonitor) labels(name string) prometheus.Labels {
+	return prometheus.Labels{
+		"docker_monitor_name": d.Label,
+		"docker_host":         d.Host,
+		"container_name":      name,
+	}
 }
 
 func (d *DockerMonitor) pushToPrometheus(data *DockerHealthCheckerData) {
@@ -158,6 +579,112 @@ func (d *DockerMonitor) pushToPrometheus(data *DockerHealthCheckerData) {
 		statusValue = 0
 	}
 
-	d.ContainerStatusMonitor.WithLabelValues(d.Label, d.ContainerName).Set(statusValue)
-	d.Logger.Printf("Docker monitor '%s' for container '%s': status = %v", d.Label, d.ContainerName, data.IsRunning)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// The history also tracks the containers with series to remove.
+	history := d.containerHistory(data.Name)
+
+	labels := d.labels(data.Name)
+	d.Metrics.Status.With(labels).Set(statusValue)
+	d.Logger.Printf("Docker monitor '%s' for container '%s': status = %v", d.Label, data.Name, data.IsRunning)
+
+	if data.Health == "" {
+		d.Metrics.Health.DeletePartialMatch(labels)
+	} else {
+		for _, state := range dockerHealthStates {
+			value := 0.0
+			if state == data.Health {
+				value = 1
+			}
+			d.Metrics.Health.MustCurryWith(labels).WithLabelValues(string(state)).Set(value)
+		}
+		d.Logger.Printf("Docker monitor '%s' for container '%s': health = %s", d.Label, data.Name, data.Health)
+	}
+
+	if data.HealthExitCode == nil {
+		d.Metrics.HealthExitCode.Delete(labels)
+	} else {
+		d.Metrics.HealthExitCode.With(labels).Set(float64(*data.HealthExitCode))
+	}
+
+	d.pushStateToPrometheus(data, history)
+}
+
+func (d *DockerMonitor) pushStateToPrometheus(data *DockerHealthCheckerData, history *dockerContainerHistory) {
+	labels := d.labels(data.Name)
+	if data.State == nil {
+		for _, collector := range d.Metrics.stateCollectors() {
+			collector.Delete(labels)
+		}
+		// A failed inspection doesn't mean that the container restarted.
+		if data.Err == nil {
+			history.crashLoop.reset()
+		}
+		return
+	}
+
+	state := data.State
+	d.Metrics.RestartCount.With(labels).Set(float64(state.RestartCount))
+	d.Metrics.ExitCode.With(labels).Set(float64(state.ExitCode))
+
+	oomKilled := 0.0
+	if state.OOMKilled {
+		d.Logger.Printf("Docker monitor '%s' for container '%s': killed because out of memory", d.Label, data.Name)
+		oomKilled = 1
+	}
+	d.Metrics.OOMKilled.With(labels).Set(oomKilled)
+
+	uptime := 0.0
+	if data.IsRunning && !state.StartedAt.IsZero() {
+		uptime = time.Since(state.StartedAt).Seconds()
+	}
+	d.Metrics.Uptime.With(labels).Set(uptime)
+
+	crashLoop := 0.0
+	if history.crashLoop.observe(time.Now(), state.RestartCount) {
+		d.Logger.Printf("Docker monitor '%s' for container '%s': crash loop detected (%d restarts)", d.Label, data.Name, state.RestartCount)
+		crashLoop = 1
+	}
+	d.Metrics.CrashLoop.With(labels).Set(crashLoop)
+}
+
+func (d *DockerMonitor) pushStatsToPrometheus(name string, stats *DockerContainerStats) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	labels := d.labels(name)
+	if stats == nil {
+		for _, collector := range d.Metrics.statsCollectors() {
+			collector.Delete(labels)
+		}
+		d.containerHistory(name).previousCPU = nil
+		return
+	}
+
+	if stats.CPUPercent == nil {
+		d.Metrics.CPU.Delete(labels)
+	} else {
+		d.Metrics.CPU.With(labels).Set(*stats.CPUPercent)
+	}
+	d.Metrics.MemoryUsage.With(labels).Set(float64(stats.MemoryUsage))
+	d.Metrics.MemoryLimit.With(labels).Set(float64(stats.MemoryLimit))
+	d.Metrics.NetworkRx.Set(labels, float64(stats.NetworkRx))
+	d.Metrics.NetworkTx.Set(labels, float64(stats.NetworkTx))
+	d.Metrics.BlockIORead.Set(labels, float64(stats.BlockIORead))
+	d.Metrics.BlockIOWrite.Set(labels, float64(stats.BlockIOWrite))
+}
+
+func (d *DockerMonitor) pushReplicasToPrometheus(running int) {
+	labels := prometheus.Labels{"docker_monitor_name": d.Label, "docker_host": d.Host}
+	d.Metrics.RunningReplicas.With(labels).Set(float64(running))
+
+	if d.ExpectedReplicas == 0 {
+		d.Metrics.ExpectedReplicas.Delete(labels)
+		return
+	}
+	d.Metrics.ExpectedReplicas.With(labels).Set(float64(d.ExpectedReplicas))
+	if running != d.ExpectedReplicas {
+		d.Logger.Printf("Docker monitor '%s': %d running replicas, %d expected", d.Label, running, d.ExpectedReplicas)
+	}
 }
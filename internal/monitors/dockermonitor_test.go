@@ -1,15 +1,19 @@
 package monitors
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"io"
 	"log"
 	"strings"
 	"testing"
+	"time"
 
 	"aireone.xyz/labtime/internal/yamlconfig"
+	cerrdefs "github.com/containerd/errdefs"
 	"github.com/docker/docker/api/types/container"
-	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
@@ -17,12 +21,38 @@ import (
 type mockDockerClient struct {
 	containers []container.Summary
 	err        error
+	// inspections are the inspect responses by container ID.
+	inspections map[string]container.InspectResponse
+	inspectErr  error
+	// inspectErrs are the inspect errors by container ID.
+	inspectErrs map[string]error
+	// stats are the stats samples by container ID.
+	stats    map[string]container.StatsResponse
+	statsErr error
 }
 
 func (m *mockDockerClient) ContainerList(_ context.Context, _ container.ListOptions) ([]container.Summary, error) {
 	return m.containers, m.err
 }
 
+func (m *mockDockerClient) ContainerInspect(_ context.Context, containerID string) (container.InspectResponse, error) {
+	if err, ok := m.inspectErrs[containerID]; ok {
+		return container.InspectResponse{}, err
+	}
+	return m.inspections[containerID], m.inspectErr
+}
+
+func (m *mockDockerClient) ContainerStatsOneShot(_ context.Context, containerID string) (container.StatsResponseReader, error) {
+	if m.statsErr != nil {
+		return container.StatsResponseReader{}, m.statsErr
+	}
+	body, err := json.Marshal(m.stats[containerID])
+	if err != nil {
+		return container.StatsResponseReader{}, err
+	}
+	return container.StatsResponseReader{Body: io.NopCloser(bytes.NewReader(body)), OSType: "linux"}, nil
+}
+
 func TestDockerTarget_GetName(t *testing.T) {
 	const expectedName = "test-container"
 
@@ -172,18 +202,14 @@ func TestDockerMonitor_Run_ContainerRunning(t *testing.T) {
 		},
 	}
 
-	// Create Prometheus gauge
-	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "test_docker_container_status",
-		Help: "Test metric",
-	}, []string{"docker_monitor_name", "container_name"})
+	collector := DockerMonitorFactory{}.CreateCollector()
 
 	monitor := &DockerMonitor{
-		Label:                  "test-container",
-		ContainerName:          "nginx",
-		Logger:                 log.Default(),
-		ContainerStatusMonitor: gauge,
-		client:                 mockClient,
+		Label:         "test-container",
+		ContainerName: "nginx",
+		Logger:        log.Default(),
+		Metrics:       collector,
+		client:        mockClient,
 	}
 
 	err := monitor.Run(t.Context())
@@ -193,7 +219,7 @@ func TestDockerMonitor_Run_ContainerRunning(t *testing.T) {
 
 	// Verify the metric value was set to 1 for running container
 	expectedValue := 1.0
-	actualValue := testutil.ToFloat64(gauge.WithLabelValues("test-container", "nginx"))
+	actualValue := testutil.ToFloat64(collector.Status.WithLabelValues("test-container", "", "nginx"))
 	if actualValue != expectedValue {
 		t.Errorf("Expected metric value %v, got %v", expectedValue, actualValue)
 	}
@@ -210,18 +236,14 @@ func TestDockerMonitor_Run_ContainerNotRunning(t *testing.T) {
 		},
 	}
 
-	// Create Prometheus gauge
-	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "test_docker_container_status",
-		Help: "Test metric",
-	}, []string{"docker_monitor_name", "container_name"})
+	collector := DockerMonitorFactory{}.CreateCollector()
 
 	monitor := &DockerMonitor{
-		Label:                  "test-container",
-		ContainerName:          "nginx",
-		Logger:                 log.Default(),
-		ContainerStatusMonitor: gauge,
-		client:                 mockClient,
+		Label:         "test-container",
+		ContainerName: "nginx",
+		Logger:        log.Default(),
+		Metrics:       collector,
+		client:        mockClient,
 	}
 
 	err := monitor.Run(t.Context())
@@ -231,7 +253,7 @@ func TestDockerMonitor_Run_ContainerNotRunning(t *testing.T) {
 
 	// Verify the metric value was set to 0 for stopped container
 	expectedValue := 0.0
-	actualValue := testutil.ToFloat64(gauge.WithLabelValues("test-container", "nginx"))
+	actualValue := testutil.ToFloat64(collector.Status.WithLabelValues("test-container", "", "nginx"))
 	if actualValue != expectedValue {
 		t.Errorf("Expected metric value %v, got %v", expectedValue, actualValue)
 	}
@@ -243,18 +265,14 @@ func TestDockerMonitor_Run_ContainerNotFound(t *testing.T) {
 		containers: []container.Summary{},
 	}
 
-	// Create Prometheus gauge
-	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "test_docker_container_status",
-		Help: "Test metric",
-	}, []string{"docker_monitor_name", "container_name"})
+	collector := DockerMonitorFactory{}.CreateCollector()
 
 	monitor := &DockerMonitor{
-		Label:                  "test-container",
-		ContainerName:          "nginx",
-		Logger:                 log.Default(),
-		ContainerStatusMonitor: gauge,
-		client:                 mockClient,
+		Label:         "test-container",
+		ContainerName: "nginx",
+		Logger:        log.Default(),
+		Metrics:       collector,
+		client:        mockClient,
 	}
 
 	err := monitor.Run(t.Context())
@@ -264,25 +282,21 @@ func TestDockerMonitor_Run_ContainerNotFound(t *testing.T) {
 
 	// Verify the metric value was set to 0 for container not found
 	expectedValue := 0.0
-	actualValue := testutil.ToFloat64(gauge.WithLabelValues("test-container", "nginx"))
+	actualValue := testutil.ToFloat64(collector.Status.WithLabelValues("test-container", "", "nginx"))
 	if actualValue != expectedValue {
 		t.Errorf("Expected metric value %v, got %v", expectedValue, actualValue)
 	}
 }
 
 func TestDockerMonitor_Run_NilClient(t *testing.T) {
-	// Create Prometheus gauge
-	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "test_docker_container_status",
-		Help: "Test metric",
-	}, []string{"docker_monitor_name", "container_name"})
+	collector := DockerMonitorFactory{}.CreateCollector()
 
 	monitor := &DockerMonitor{
-		Label:                  "test-container",
-		ContainerName:          "nginx",
-		Logger:                 log.Default(),
-		ContainerStatusMonitor: gauge,
-		client:                 nil, // No client
+		Label:         "test-container",
+		ContainerName: "nginx",
+		Logger:        log.Default(),
+		Metrics:       collector,
+		client:        nil, // No client
 	}
 
 	err := monitor.Run(t.Context())
@@ -303,18 +317,14 @@ func TestDockerMonitor_Run_ClientError(t *testing.T) {
 		err:        errors.New("failed to connect to Docker daemon"),
 	}
 
-	// Create Prometheus gauge
-	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "test_docker_container_status",
-		Help: "Test metric",
-	}, []string{"docker_monitor_name", "container_name"})
+	collector := DockerMonitorFactory{}.CreateCollector()
 
 	monitor := &DockerMonitor{
-		Label:                  "test-container",
-		ContainerName:          "nginx",
-		Logger:                 log.Default(),
-		ContainerStatusMonitor: gauge,
-		client:                 mockClient,
+		Label:         "test-container",
+		ContainerName: "nginx",
+		Logger:        log.Default(),
+		Metrics:       collector,
+		client:        mockClient,
 	}
 
 	err := monitor.Run(t.Context())
@@ -339,18 +349,14 @@ func TestDockerMonitor_Run_ContainerNameWithoutSlash(t *testing.T) {
 		},
 	}
 
-	// Create Prometheus gauge
-	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "test_docker_container_status",
-		Help: "Test metric",
-	}, []string{"docker_monitor_name", "container_name"})
+	collector := DockerMonitorFactory{}.CreateCollector()
 
 	monitor := &DockerMonitor{
-		Label:                  "test-container",
-		ContainerName:          "nginx",
-		Logger:                 log.Default(),
-		ContainerStatusMonitor: gauge,
-		client:                 mockClient,
+		Label:         "test-container",
+		ContainerName: "nginx",
+		Logger:        log.Default(),
+		Metrics:       collector,
+		client:        mockClient,
 	}
 
 	err := monitor.Run(t.Context())
@@ -360,8 +366,266 @@ func TestDockerMonitor_Run_ContainerNameWithoutSlash(t *testing.T) {
 
 	// Verify the metric value was set to 1 for running container
 	expectedValue := 1.0
-	actualValue := testutil.ToFloat64(gauge.WithLabelValues("test-container", "nginx"))
+	actualValue := testutil.ToFloat64(collector.Status.WithLabelValues("test-container", "", "nginx"))
 	if actualValue != expectedValue {
 		t.Errorf("Expected metric value %v, got %v", expectedValue, actualValue)
 	}
 }
+
+// newInspectResponse returns an inspect response with the container state.
+func newInspectResponse(state *container.State) container.InspectResponse {
+	return container.InspectResponse{
+		ContainerJSONBase: &container.ContainerJSONBase{State: state},
+	}
+}
+
+func TestDockerMonitor_Run_Health(t *testing.T) {
+	tests := []struct {
+		name             string
+		state            *container.State
+		expectedHealth   container.HealthStatus
+		expectedExitCode float64
+		hasExitCode      bool
+	}{
+		{
+			name:           "no health check",
+			state:          &container.State{Running: true},
+			expectedHealth: container.NoHealthcheck,
+		},
+		{
+			name: "healthy",
+			state: &container.State{Running: true, Health: &container.Health{
+				Status: container.Healthy,
+				Log:    []*container.HealthcheckResult{{ExitCode: 1}, {ExitCode: 0}},
+			}},
+			expectedHealth:   container.Healthy,
+			expectedExitCode: 0,
+			hasExitCode:      true,
+		},
+		{
+			name: "unhealthy",
+			state: &container.State{Running: true, Health: &container.Health{
+				Status:        container.Unhealthy,
+				FailingStreak: 3,
+				Log:           []*container.HealthcheckResult{{ExitCode: 1}},
+			}},
+			expectedHealth:   container.Unhealthy,
+			expectedExitCode: 1,
+			hasExitCode:      true,
+		},
+		{
+			name:           "starting",
+			state:          &container.State{Running: true, Health: &container.Health{Status: container.Starting}},
+			expectedHealth: container.Starting,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := DockerMonitorFactory{}.CreateCollector()
+			monitor := &DockerMonitor{
+				Label:         "test-container",
+				ContainerName: "nginx",
+				Logger:        log.Default(),
+				Metrics:       collector,
+				client: &mockDockerClient{
+					containers:  []container.Summary{{ID: "abc", Names: []string{"/nginx"}, State: "running"}},
+					inspections: map[string]container.InspectResponse{"abc": newInspectResponse(tt.state)},
+				},
+			}
+
+			if err := monitor.Run(t.Context()); err != nil {
+				t.Fatalf("Run() returned error: %v", err)
+			}
+
+			for _, state := range dockerHealthStates {
+				expected := 0.0
+				if state == tt.expectedHealth {
+					expected = 1
+				}
+				actual := testutil.ToFloat64(collector.Health.WithLabelValues("test-container", "", "nginx", string(state)))
+				if actual != expected {
+					t.Errorf("Health %s: expected %v, got %v", state, expected, actual)
+				}
+			}
+
+			count := testutil.CollectAndCount(collector, "labtime_docker_container_health_exit_code")
+			if !tt.hasExitCode {
+				if count != 0 {
+					t.Errorf("Expected no health exit code, got %d series", count)
+				}
+				return
+			}
+			actual := testutil.ToFloat64(collector.HealthExitCode.WithLabelValues("test-container", "", "nginx"))
+			if actual != tt.expectedExitCode {
+				t.Errorf("Health exit code: expected %v, got %v", tt.expectedExitCode, actual)
+			}
+		})
+	}
+}
+
+func TestDockerMonitor_Run_InspectError(t *testing.T) {
+	monitor := &DockerMonitor{
+		Label:         "test-container",
+		ContainerName: "nginx",
+		Logger:        log.Default(),
+		Metrics:       DockerMonitorFactory{}.CreateCollector(),
+		client: &mockDockerClient{
+			containers: []container.Summary{{ID: "abc", Names: []string{"/nginx"}, State: "running"}},
+			inspectErr: errors.New("no such container"),
+		},
+	}
+
+	if err := monitor.Run(t.Context()); err == nil {
+		t.Error("Run() should return error when the container inspection fails")
+	}
+	// The status from the container list is still exported.
+	if status := testutil.ToFloat64(monitor.Metrics.Status.WithLabelValues("test-container", "", "nginx")); status != 1 {
+		t.Errorf("Expected status 1, got %v", status)
+	}
+}
+
+func TestDockerMonitor_Run_InspectErrorPartial(t *testing.T) {
+	labels := map[string]string{composeServiceLabel: "web"}
+	collector := DockerMonitorFactory{}.CreateCollector()
+	monitor := &DockerMonitor{
+		Label:   "web",
+		Labels:  labels,
+		Logger:  log.Default(),
+		Metrics: collector,
+		client: &mockDockerClient{
+			containers: []container.Summary{
+				{ID: "1", Names: []string{"/web-1"}, Labels: labels, State: "running"},
+				{ID: "2", Names: []string{"/web-2"}, Labels: labels, State: "running"},
+				{ID: "3", Names: []string{"/web-3"}, Labels: labels, State: "running"},
+			},
+			inspections: map[string]container.InspectResponse{
+				"1": newInspectResponse(&container.State{Status: "running", Running: true}),
+			},
+			inspectErrs: map[string]error{
+				"2": cerrdefs.ErrNotFound.WithMessage("No such container: 2"),
+				"3": errors.New("connection reset"),
+			},
+		},
+	}
+
+	err := monitor.Run(t.Context())
+	if err == nil || !strings.Contains(err.Error(), "web-3") || strings.Contains(err.Error(), "web-2") {
+		t.Errorf("Expected only the web-3 inspection error, got %v", err)
+	}
+
+	// The removed container is skipped, the others are still exported.
+	if count := testutil.CollectAndCount(collector, "labtime_docker_container_status"); count != 2 {
+		t.Errorf("Expected 2 status series, got %d", count)
+	}
+	for _, name := range []string{"web-1", "web-3"} {
+		if status := testutil.ToFloat64(collector.Status.WithLabelValues("web", "", name)); status != 1 {
+			t.Errorf("%s status: expected 1, got %v", name, status)
+		}
+	}
+	if count := testutil.CollectAndCount(collector, "labtime_docker_container_restart_count"); count != 1 {
+		t.Errorf("Expected the state of web-1 only, got %d series", count)
+	}
+	if running := testutil.ToFloat64(collector.RunningReplicas.WithLabelValues("web", "")); running != 2 {
+		t.Errorf("running replicas: expected 2, got %v", running)
+	}
+}
+
+func TestDockerTargetProvider_GetTargets_CrashLoop(t *testing.T) {
+	provider := DockerTargetProvider{}
+
+	targets, err := provider.GetTargets(&yamlconfig.YamlConfig{
+		DockerMonitors: []yamlconfig.DockerMonitorDTO{
+			{ContainerName: "nginx"},
+			{ContainerName: "nginx", CrashLoopRestarts: 5, CrashLoopWindow: 600},
+		},
+	})
+	if err != nil {
+		t.Fatalf("GetTargets() returned unexpected error: %v", err)
+	}
+	if targets[0].CrashLoopRestarts != 3 || targets[0].CrashLoopWindow != 300 {
+		t.Errorf("Unexpected default crash loop settings: %+v", targets[0])
+	}
+	if targets[1].CrashLoopRestarts != 5 || targets[1].CrashLoopWindow != 600 {
+		t.Errorf("Unexpected crash loop settings: %+v", targets[1])
+	}
+
+	if _, err := provider.GetTargets(&yamlconfig.YamlConfig{
+		DockerMonitors: []yamlconfig.DockerMonitorDTO{{ContainerName: "nginx", CrashLoopWindow: -1}},
+	}); err == nil {
+		t.Error("Expected error for invalid crash loop window but got none")
+	}
+}
+
+func TestDockerMonitor_Run_State(t *testing.T) {
+	startedAt := time.Now().Add(-time.Hour)
+	mockClient := &mockDockerClient{
+		containers: []container.Summary{{ID: "abc", Names: []string{"/nginx"}, State: "running"}},
+		inspections: map[string]container.InspectResponse{
+			"abc": {
+				ContainerJSONBase: &container.ContainerJSONBase{
+					RestartCount: 4,
+					State: &container.State{
+						Running:   true,
+						ExitCode:  137,
+						OOMKilled: true,
+						StartedAt: startedAt.Format(time.RFC3339Nano),
+					},
+				},
+			},
+		},
+	}
+	collector := DockerMonitorFactory{}.CreateCollector()
+	monitor := &DockerMonitor{
+		Label:             "test-container",
+		ContainerName:     "nginx",
+		Logger:            log.Default(),
+		Metrics:           collector,
+		client:            mockClient,
+		CrashLoopRestarts: 1,
+	}
+
+	if err := monitor.Run(t.Context()); err != nil {
+		t.Fatalf("Run() returned error: %v", err)
+	}
+
+	expected := map[string]float64{
+		"restart_count": 4,
+		"exit_code":     137,
+		"oom_killed":    1,
+		"crash_loop":    0,
+	}
+	actual := map[string]float64{
+		"restart_count": testutil.ToFloat64(collector.RestartCount.WithLabelValues("test-container", "", "nginx")),
+		"exit_code":     testutil.ToFloat64(collector.ExitCode.WithLabelValues("test-container", "", "nginx")),
+		"oom_killed":    testutil.ToFloat64(collector.OOMKilled.WithLabelValues("test-container", "", "nginx")),
+		"crash_loop":    testutil.ToFloat64(collector.CrashLoop.WithLabelValues("test-container", "", "nginx")),
+	}
+	for name, value := range expected {
+		if actual[name] != value {
+			t.Errorf("%s: expected %v, got %v", name, value, actual[name])
+		}
+	}
+	uptime := testutil.ToFloat64(collector.Uptime.WithLabelValues("test-container", "", "nginx"))
+	if abs(uptime-time.Hour.Seconds()) > 5 {
+		t.Errorf("uptime: expected about %v, got %v", time.Hour.Seconds(), uptime)
+	}
+
+	// A restart since the previous run flags the crash loop.
+	mockClient.inspections["abc"].RestartCount = 5
+	if err := monitor.Run(t.Context()); err != nil {
+		t.Fatalf("Run() returned error: %v", err)
+	}
+	if crashLoop := testutil.ToFloat64(collector.CrashLoop.WithLabelValues("test-container", "", "nginx")); crashLoop != 1 {
+		t.Errorf("crash_loop: expected 1, got %v", crashLoop)
+	}
+
+	// The state metrics are removed when the container disappears.
+	mockClient.containers = nil
+	if err := monitor.Run(t.Context()); err != nil {
+		t.Fatalf("Run() returned error: %v", err)
+	}
+	if count := testutil.CollectAndCount(collector, "labtime_docker_container_restart_count"); count != 0 {
+		t.Errorf("Expected no restart count series, got %d", count)
+	}
+}
@@ -0,0 +1,61 @@
+package monitors
+
+import (
+	"time"
+)
+
+// Default crash loop detection settings of the Docker monitors.
+const (
+	defaultCrashLoopRestarts = 3
+	defaultCrashLoopWindow   = 5 * time.Minute
+)
+
+// restartSample is the restart count of a container observed at a time.
+type restartSample struct {
+	Time  time.Time
+	Count int
+}
+
+// crashLoopDetector tracks the restart count of a container to detect crash
+// loops: at least Restarts restarts within the Window.
+type crashLoopDetector struct {
+	Restarts int
+	Window   time.Duration
+
+	samples []restartSample
+}
+
+// observe records the restart count and tells whether the container is crash
+// looping.
+func (c *crashLoopDetector) observe(now time.Time, count int) bool {
+	restarts, window := c.Restarts, c.Window
+	if restarts == 0 {
+		restarts = defaultCrashLoopRestarts
+	}
+	if window == 0 {
+		window = defaultCrashLoopWindow
+	}
+
+	// The count is reset when the container is recreated, the previous
+	// samples are not comparable anymore.
+	if n := len(c.samples); n > 0 && count < c.samples[n-1].Count {
+		c.samples = nil
+	}
+	c.samples = append(c.samples, restartSample{Time: now, Count: count})
+
+	// The baseline is the last sample taken at least a window ago, or the
+	// first one. Keeping it counts the restarts of the whole window, even when
+	// the checks are less frequent than the window.
+	first := 0
+	for first < len(c.samples)-1 && now.Sub(c.samples[first+1].Time) >= window {
+		first++
+	}
+	c.samples = c.samples[first:]
+
+	return count-c.samples[0].Count >= restarts
+}
+
+// reset forgets the observed restart counts.
+func (c *crashLoopDetector) reset() {
+	c.samples = nil
+}
@@ -0,0 +1,72 @@
+package monitors
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCrashLoopDetector_observe(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		counts   []int
+		interval time.Duration
+		expected []bool
+	}{
+		{
+			name:     "stable",
+			counts:   []int{2, 2, 2, 2},
+			interval: time.Minute,
+			expected: []bool{false, false, false, false},
+		},
+		{
+			name:     "restarting within the window",
+			counts:   []int{0, 1, 2, 3, 3},
+			interval: time.Minute,
+			expected: []bool{false, false, false, true, true},
+		},
+		{
+			name:     "restarts spread over more than the window",
+			counts:   []int{0, 1, 2, 3},
+			interval: 3 * time.Minute,
+			expected: []bool{false, false, false, false},
+		},
+		{
+			name:     "checks less frequent than the window",
+			counts:   []int{0, 3, 6, 6},
+			interval: 10 * time.Minute,
+			expected: []bool{false, true, true, false},
+		},
+		{
+			name:     "recreated container",
+			counts:   []int{5, 0, 1},
+			interval: time.Minute,
+			expected: []bool{false, false, false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			detector := &crashLoopDetector{}
+			for i, count := range tt.counts {
+				now := start.Add(time.Duration(i) * tt.interval)
+				if got := detector.observe(now, count); got != tt.expected[i] {
+					t.Errorf("observe(%d) at step %d = %v, want %v", count, i, got, tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCrashLoopDetector_observe_Settings(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	detector := &crashLoopDetector{Restarts: 1, Window: 10 * time.Minute}
+
+	if detector.observe(start, 0) {
+		t.Error("Expected no crash loop on the first observation")
+	}
+	if !detector.observe(start.Add(9*time.Minute), 1) {
+		t.Error("Expected a crash loop after 1 restart within 10 minutes")
+	}
+}
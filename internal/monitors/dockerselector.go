@@ -0,0 +1,80 @@
+package monitors
+
+import (
+	"strings"
+
+	"aireone.xyz/labtime/internal/yamlconfig"
+	"github.com/docker/docker/api/types/container"
+	"github.com/pkg/errors"
+)
+
+// Labels set by Docker Compose on the containers of a project.
+const (
+	composeProjectLabel = "com.docker.compose.project"
+	composeServiceLabel = "com.docker.compose.service"
+)
+
+// dockerSelectorLabels returns the labels selecting the containers of the
+// target, including the compose project and service labels.
+func dockerSelectorLabels(dto yamlconfig.DockerMonitorDTO) (map[string]string, error) {
+	labels := make(map[string]string, len(dto.Labels)+2)
+	for key, value := range dto.Labels {
+		if key == "" {
+			return nil, errors.New("empty label name")
+		}
+		labels[key] = value
+	}
+	for key, value := range map[string]string{composeProjectLabel: dto.ComposeProject, composeServiceLabel: dto.ComposeService} {
+		if value == "" {
+			continue
+		}
+		if existing, ok := labels[key]; ok && existing != value {
+			return nil, errors.Errorf("conflicting values for label %s", key)
+		}
+		labels[key] = value
+	}
+	if len(labels) == 0 {
+		return nil, nil
+	}
+	return labels, nil
+}
+
+// containerName returns the name of the container without the leading
+// slash of the Docker API.
+func containerName(c container.Summary) string {
+	if len(c.Names) == 0 {
+		return ""
+	}
+	return strings.TrimPrefix(c.Names[0], "/")
+}
+
+// matches tells whether the container is selected by the monitor and returns
+// its name. An empty label value only requires the label to be set.
+func (d *DockerMonitor) matches(c container.Summary) (string, bool) {
+	name := containerName(c)
+	if d.ContainerName != "" {
+		found := false
+		for _, n := range c.Names {
+			if strings.TrimPrefix(n, "/") == d.ContainerName {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return "", false
+		}
+		name = d.ContainerName
+	}
+
+	if d.NamePattern != nil && !d.NamePattern.MatchString(name) {
+		return "", false
+	}
+
+	for key, value := range d.Labels {
+		actual, ok := c.Labels[key]
+		if !ok || (value != "" && actual != value) {
+			return "", false
+		}
+	}
+	return name, true
+}
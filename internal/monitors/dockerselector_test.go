@@ -0,0 +1,219 @@
+package monitors
+
+import (
+	"io"
+	"log"
+	"regexp"
+	"sync"
+	"testing"
+
+	"aireone.xyz/labtime/internal/yamlconfig"
+	"github.com/docker/docker/api/types/container"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestDockerSelectorLabels(t *testing.T) {
+	labels, err := dockerSelectorLabels(yamlconfig.DockerMonitorDTO{
+		Labels:         map[string]string{"traefik.enable": "true"},
+		ComposeProject: "homelab",
+		ComposeService: "web",
+	})
+	if err != nil {
+		t.Fatalf("dockerSelectorLabels() error = %v", err)
+	}
+	expected := map[string]string{
+		"traefik.enable":    "true",
+		composeProjectLabel: "homelab",
+		composeServiceLabel: "web",
+	}
+	if len(labels) != len(expected) {
+		t.Fatalf("dockerSelectorLabels() = %v, want %v", labels, expected)
+	}
+	for key, value := range expected {
+		if labels[key] != value {
+			t.Errorf("label %s = %q, want %q", key, labels[key], value)
+		}
+	}
+
+	if labels, err := dockerSelectorLabels(yamlconfig.DockerMonitorDTO{ContainerName: "nginx"}); err != nil || labels != nil {
+		t.Errorf("dockerSelectorLabels() = %v, %v, want nil, nil", labels, err)
+	}
+
+	if _, err := dockerSelectorLabels(yamlconfig.DockerMonitorDTO{
+		Labels:         map[string]string{composeProjectLabel: "other"},
+		ComposeProject: "homelab",
+	}); err == nil {
+		t.Error("dockerSelectorLabels() with conflicting labels should fail")
+	}
+}
+
+func TestDockerMonitor_matches(t *testing.T) {
+	web := container.Summary{
+		Names:  []string{"/homelab-web-1"},
+		Labels: map[string]string{composeProjectLabel: "homelab", composeServiceLabel: "web", "traefik.enable": "true"},
+	}
+
+	tests := []struct {
+		name     string
+		monitor  *DockerMonitor
+		expected bool
+	}{
+		{name: "container name", monitor: &DockerMonitor{ContainerName: "homelab-web-1"}, expected: true},
+		{name: "other container name", monitor: &DockerMonitor{ContainerName: "homelab-web-2"}, expected: false},
+		{name: "compose service", monitor: &DockerMonitor{Labels: map[string]string{composeServiceLabel: "web"}}, expected: true},
+		{name: "other compose service", monitor: &DockerMonitor{Labels: map[string]string{composeServiceLabel: "db"}}, expected: false},
+		{name: "label set", monitor: &DockerMonitor{Labels: map[string]string{"traefik.enable": ""}}, expected: true},
+		{name: "label not set", monitor: &DockerMonitor{Labels: map[string]string{"labtime": ""}}, expected: false},
+		{name: "name pattern", monitor: &DockerMonitor{NamePattern: regexp.MustCompile(`^homelab-web-\d+$`)}, expected: true},
+		{name: "other name pattern", monitor: &DockerMonitor{NamePattern: regexp.MustCompile(`^db`)}, expected: false},
+		{
+			name: "all criteria",
+			monitor: &DockerMonitor{
+				NamePattern: regexp.MustCompile(`web`),
+				Labels:      map[string]string{composeProjectLabel: "homelab", "traefik.enable": "false"},
+			},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, ok := tt.monitor.matches(web)
+			if ok != tt.expected {
+				t.Fatalf("matches() = %v, want %v", ok, tt.expected)
+			}
+			if ok && name != "homelab-web-1" {
+				t.Errorf("matches() name = %s, want homelab-web-1", name)
+			}
+		})
+	}
+}
+
+func TestDockerTargetProvider_GetTargets_Selector(t *testing.T) {
+	provider := DockerTargetProvider{}
+
+	targets, err := provider.GetTargets(&yamlconfig.YamlConfig{
+		DockerMonitors: []yamlconfig.DockerMonitorDTO{
+			{ComposeProject: "homelab", ComposeService: "web", ExpectedReplicas: 2},
+			{Name: "traefik", Labels: map[string]string{"traefik.enable": "true"}},
+			{NamePattern: "^db-"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("GetTargets() returned unexpected error: %v", err)
+	}
+	if targets[0].Name != "web" || targets[0].ExpectedReplicas != 2 || targets[0].Labels[composeProjectLabel] != "homelab" {
+		t.Errorf("Unexpected compose target: %+v", targets[0])
+	}
+	if targets[1].Name != "traefik" || targets[1].Labels["traefik.enable"] != "true" {
+		t.Errorf("Unexpected labels target: %+v", targets[1])
+	}
+	if targets[2].Name != "^db-" || targets[2].NamePattern == nil {
+		t.Errorf("Unexpected name pattern target: %+v", targets[2])
+	}
+
+	invalid := map[string]yamlconfig.DockerMonitorDTO{
+		"no selector":       {Name: "empty"},
+		"no name":           {Labels: map[string]string{"traefik.enable": "true"}},
+		"invalid pattern":   {NamePattern: "("},
+		"negative replicas": {ContainerName: "nginx", ExpectedReplicas: -1},
+	}
+	for name, dto := range invalid {
+		t.Run(name, func(t *testing.T) {
+			if _, err := provider.GetTargets(&yamlconfig.YamlConfig{
+				DockerMonitors: []yamlconfig.DockerMonitorDTO{dto},
+			}); err == nil {
+				t.Error("Expected error but got none")
+			}
+		})
+	}
+}
+
+func TestDockerMonitor_Run_Replicas(t *testing.T) {
+	labels := map[string]string{composeProjectLabel: "homelab", composeServiceLabel: "web"}
+	mockClient := &mockDockerClient{
+		containers: []container.Summary{
+			{ID: "1", Names: []string{"/homelab-web-1"}, Labels: labels, State: "running"},
+			{ID: "2", Names: []string{"/homelab-web-2"}, Labels: labels, State: "exited"},
+			{ID: "3", Names: []string{"/homelab-db-1"}, Labels: map[string]string{composeProjectLabel: "homelab"}, State: "running"},
+		},
+	}
+	collector := DockerMonitorFactory{}.CreateCollector()
+	monitor := &DockerMonitor{
+		Label:            "web",
+		Labels:           map[string]string{composeServiceLabel: "web"},
+		ExpectedReplicas: 2,
+		Logger:           log.Default(),
+		Metrics:          collector,
+		client:           mockClient,
+	}
+
+	if err := monitor.Run(t.Context()); err != nil {
+		t.Fatalf("Run() returned error: %v", err)
+	}
+
+	if status := testutil.ToFloat64(collector.Status.WithLabelValues("web", "", "homelab-web-1")); status != 1 {
+		t.Errorf("homelab-web-1 status: expected 1, got %v", status)
+	}
+	if status := testutil.ToFloat64(collector.Status.WithLabelValues("web", "", "homelab-web-2")); status != 0 {
+		t.Errorf("homelab-web-2 status: expected 0, got %v", status)
+	}
+	if count := testutil.CollectAndCount(collector, "labtime_docker_container_status"); count != 2 {
+		t.Errorf("Expected 2 status series, got %d", count)
+	}
+	if running := testutil.ToFloat64(collector.RunningReplicas.WithLabelValues("web", "")); running != 1 {
+		t.Errorf("running replicas: expected 1, got %v", running)
+	}
+	if expected := testutil.ToFloat64(collector.ExpectedReplicas.WithLabelValues("web", "")); expected != 2 {
+		t.Errorf("expected replicas: expected 2, got %v", expected)
+	}
+
+	// The series of a removed replica are deleted.
+	mockClient.containers = mockClient.containers[:1]
+	if err := monitor.Run(t.Context()); err != nil {
+		t.Fatalf("Run() returned error: %v", err)
+	}
+	if count := testutil.CollectAndCount(collector, "labtime_docker_container_status"); count != 1 {
+		t.Errorf("Expected 1 status series, got %d", count)
+	}
+	if count := testutil.CollectAndCount(collector, "labtime_docker_container_health"); count != 4 {
+		t.Errorf("Expected 4 health series, got %d", count)
+	}
+}
+
+func TestDockerMonitor_Run_Overlapping(t *testing.T) {
+	labels := map[string]string{composeServiceLabel: "web"}
+	mockClient := &mockDockerClient{
+		containers: []container.Summary{
+			{ID: "1", Names: []string{"/web-1"}, Labels: labels, State: "running"},
+			{ID: "2", Names: []string{"/web-2"}, Labels: labels, State: "running"},
+		},
+		stats: map[string]container.StatsResponse{
+			"1": {CPUStats: container.CPUStats{SystemUsage: 10000}},
+			"2": {CPUStats: container.CPUStats{SystemUsage: 10000}},
+		},
+	}
+	monitor := &DockerMonitor{
+		Label:   "web",
+		Labels:  labels,
+		Stats:   true,
+		Logger:  log.New(io.Discard, "", 0),
+		Metrics: DockerMonitorFactory{}.CreateCollector(),
+		client:  mockClient,
+	}
+
+	// The scheduler starts a run even if the previous one is not done.
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 20 {
+				if err := monitor.Run(t.Context()); err != nil {
+					t.Errorf("Run() returned error: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,189 @@
+package monitors
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// dockerSSHDaemonHost is the placeholder host of the requests sent through an
+// ssh connection, the dialer ignores the address.
+const dockerSSHDaemonHost = "http://docker.example.com"
+
+// dockerSSHHost is an ssh://[user@]host[:port][/socket] Docker host.
+type dockerSSHHost struct {
+	User string
+	Host string
+	Port string
+	// Socket is the path of the Docker socket on the remote host, empty for
+	// the default socket.
+	Socket string
+}
+
+// parseDockerSSHHost validates an ssh:// Docker host. The passwords are
+// rejected, the authentication is left to the ssh configuration.
+func parseDockerSSHHost(host string) (*dockerSSHHost, error) {
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid ssh host")
+	}
+	if u.Scheme != "ssh" {
+		return nil, errors.Errorf("expected scheme ssh, got %q", u.Scheme)
+	}
+
+	var sshHost dockerSSHHost
+	if u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			return nil, errors.New("plain-text password is not supported in ssh host")
+		}
+		sshHost.User = u.User.Username()
+	}
+	sshHost.Host = u.Hostname()
+	if sshHost.Host == "" {
+		return nil, errors.New("no host in ssh host")
+	}
+	sshHost.Port = u.Port()
+	sshHost.Socket = u.Path
+	if u.RawQuery != "" || u.Fragment != "" {
+		return nil, errors.New("unexpected query or fragment in ssh host")
+	}
+	return &sshHost, nil
+}
+
+// args returns the arguments of the ssh command running "docker system
+// dial-stdio" on the remote host.
+func (h *dockerSSHHost) args() []string {
+	args := []string{"-o", "ConnectTimeout=30"}
+	if h.User != "" {
+		args = append(args, "-l", h.User)
+	}
+	if h.Port != "" {
+		args = append(args, "-p", h.Port)
+	}
+	args = append(args, "--", h.Host, "docker")
+	if h.Socket != "" {
+		args = append(args, "--host", "unix://"+h.Socket)
+	}
+	return append(args, "system", "dial-stdio")
+}
+
+// dial connects to the remote Docker daemon through ssh.
+func (h *dockerSSHHost) dial(ctx context.Context, _, _ string) (net.Conn, error) {
+	return newCommandConn(ctx, "ssh", h.args()...)
+}
+
+// commandConn is a connection to the standard input and output of a command.
+// Closing the connection stops the command.
+type commandConn struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout io.ReadCloser
+	stderr commandStderr
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// commandStderr keeps the error output of a command, written while the
+// connection is read.
+type commandStderr struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+// Write implements io.Writer.
+func (s *commandStderr) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.buf.Write(p)
+}
+
+func (s *commandStderr) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return strings.TrimSpace(s.buf.String())
+}
+
+// newCommandConn starts the command. The command outlives the context, which
+// only bounds the dial.
+func newCommandConn(ctx context.Context, name string, args ...string) (net.Conn, error) {
+	//nolint:gosec // the command and its arguments come from the docker_hosts configuration
+	cmd := exec.CommandContext(context.WithoutCancel(ctx), name, args...)
+	c := &commandConn{cmd: cmd}
+	cmd.Stderr = &c.stderr
+
+	var err error
+	if c.stdin, err = cmd.StdinPipe(); err != nil {
+		return nil, errors.Wrapf(err, "error connecting to %s", name)
+	}
+	if c.stdout, err = cmd.StdoutPipe(); err != nil {
+		return nil, errors.Wrapf(err, "error connecting to %s", name)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, errors.Wrapf(err, "error starting %s", name)
+	}
+	return c, nil
+}
+
+// Read implements net.Conn. The errors of the command are added to the read
+// errors other than io.EOF.
+func (c *commandConn) Read(p []byte) (int, error) {
+	n, err := c.stdout.Read(p)
+	if err != nil && !errors.Is(err, io.EOF) {
+		if stderr := c.stderr.String(); stderr != "" {
+			err = errors.Wrapf(err, "command error: %s", stderr)
+		}
+	}
+	return n, err
+}
+
+// Write implements net.Conn.
+func (c *commandConn) Write(p []byte) (int, error) {
+	return c.stdin.Write(p)
+}
+
+// Close implements net.Conn. It stops the command.
+func (c *commandConn) Close() error {
+	c.closeOnce.Do(func() {
+		_ = c.stdin.Close()
+		if err := c.cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+			c.closeErr = errors.Wrap(err, "error stopping command")
+		}
+		_ = c.cmd.Wait()
+	})
+	return c.closeErr
+}
+
+// commandAddr is the address of both ends of a commandConn.
+type commandAddr struct{}
+
+// Network implements net.Addr.
+func (commandAddr) Network() string { return "command" }
+
+// String implements net.Addr.
+func (commandAddr) String() string { return "command" }
+
+// LocalAddr implements net.Conn.
+func (c *commandConn) LocalAddr() net.Addr { return commandAddr{} }
+
+// RemoteAddr implements net.Conn.
+func (c *commandConn) RemoteAddr() net.Addr { return commandAddr{} }
+
+// SetDeadline implements net.Conn. The deadlines are not supported, the
+// requests are bounded by their context.
+func (c *commandConn) SetDeadline(_ time.Time) error { return nil }
+
+// SetReadDeadline implements net.Conn.
+func (c *commandConn) SetReadDeadline(_ time.Time) error { return nil }
+
+// SetWriteDeadline implements net.Conn.
+func (c *commandConn) SetWriteDeadline(_ time.Time) error { return nil }
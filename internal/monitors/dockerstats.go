@@ -0,0 +1,200 @@
+package monitors
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DockerContainerStats holds the resource usage of a container.
+type DockerContainerStats struct {
+	// CPUPercent is the CPU usage since the previous sample, 100 being one
+	// full core. It is nil when no previous sample is available.
+	CPUPercent   *float64
+	MemoryUsage  uint64
+	MemoryLimit  uint64
+	NetworkRx    uint64
+	NetworkTx    uint64
+	BlockIORead  uint64
+	BlockIOWrite uint64
+}
+
+// containerStats reads a one-shot sample of the container resource usage.
+func (d *DockerMonitor) containerStats(ctx context.Context, status *DockerHealthCheckerData) (*DockerContainerStats, error) {
+	reader, err := d.client.ContainerStatsOneShot(ctx, status.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get container stats")
+	}
+	defer reader.Body.Close()
+
+	var response container.StatsResponse
+	if err := json.NewDecoder(reader.Body).Decode(&response); err != nil {
+		return nil, errors.Wrap(err, "failed to decode container stats")
+	}
+
+	// One-shot samples don't include the previous CPU usage, the sample of
+	// the previous run is used instead.
+	d.mu.Lock()
+	history := d.containerHistory(status.Name)
+	previous := response.PreCPUStats
+	if previous.SystemUsage == 0 && history.previousCPU != nil {
+		previous = *history.previousCPU
+	}
+	history.previousCPU = &response.CPUStats
+	d.mu.Unlock()
+
+	stats := &DockerContainerStats{
+		CPUPercent:  cpuPercent(previous, response.CPUStats),
+		MemoryUsage: memoryUsage(response.MemoryStats),
+		MemoryLimit: response.MemoryStats.Limit,
+	}
+	for _, network := range response.Networks {
+		stats.NetworkRx += network.RxBytes
+		stats.NetworkTx += network.TxBytes
+	}
+	for _, entry := range response.BlkioStats.IoServiceBytesRecursive {
+		switch strings.ToLower(entry.Op) {
+		case "read":
+			stats.BlockIORead += entry.Value
+		case "write":
+			stats.BlockIOWrite += entry.Value
+		}
+	}
+	return stats, nil
+}
+
+// cpuPercent computes the CPU usage between two samples the way the docker
+// stats command does. It returns nil when the samples can't be compared.
+func cpuPercent(previous, current container.CPUStats) *float64 {
+	if previous.SystemUsage == 0 || current.SystemUsage <= previous.SystemUsage ||
+		current.CPUUsage.TotalUsage < previous.CPUUsage.TotalUsage {
+		return nil
+	}
+
+	cpus := float64(current.OnlineCPUs)
+	if cpus == 0 {
+		cpus = float64(len(current.CPUUsage.PercpuUsage))
+	}
+	cpuDelta := float64(current.CPUUsage.TotalUsage - previous.CPUUsage.TotalUsage)
+	systemDelta := float64(current.SystemUsage - previous.SystemUsage)
+	percent := cpuDelta / systemDelta * cpus * 100
+	return &percent
+}
+
+// memoryUsage returns the memory used by the container without the page
+// cache, the way the docker stats command does.
+func memoryUsage(stats container.MemoryStats) uint64 {
+	// cgroup v1 reports total_inactive_file, cgroup v2 inactive_file.
+	inactive, ok := stats.Stats["total_inactive_file"]
+	if !ok {
+		inactive = stats.Stats["inactive_file"]
+	}
+	if inactive > stats.Usage {
+		return stats.Usage
+	}
+	return stats.Usage - inactive
+}
+
+// dockerCounterVec exports totals kept by Docker as counters. The values read
+// from Docker are stored as is and exported as constant metrics when
+// collected, a counter of the client library can only be incremented.
+type dockerCounterVec struct {
+	desc       *prometheus.Desc
+	labelNames []string
+
+	mu     sync.Mutex
+	values map[string]dockerCounterValue
+}
+
+// dockerCounterValue is the value of a counter and its label values.
+type dockerCounterValue struct {
+	labelValues []string
+	value       float64
+}
+
+func newDockerCounterVec(name, help string, labelNames []string) *dockerCounterVec {
+	return &dockerCounterVec{
+		desc:       prometheus.NewDesc(name, help, labelNames, nil),
+		labelNames: labelNames,
+		values:     make(map[string]dockerCounterValue),
+	}
+}
+
+// labelValues returns the values of labels in the order of the label names
+// and the key of the series.
+func (v *dockerCounterVec) labelValues(labels prometheus.Labels) ([]string, string) {
+	values := make([]string, len(v.labelNames))
+	for i, name := range v.labelNames {
+		values[i] = labels[name]
+	}
+	return values, strings.Join(values, "\xff")
+}
+
+// Set sets the total of the series with the given labels.
+func (v *dockerCounterVec) Set(labels prometheus.Labels, value float64) {
+	values, key := v.labelValues(labels)
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.values[key] = dockerCounterValue{labelValues: values, value: value}
+}
+
+// Delete removes the series with exactly the given labels.
+func (v *dockerCounterVec) Delete(labels prometheus.Labels) bool {
+	if len(labels) != len(v.labelNames) {
+		return false
+	}
+	_, key := v.labelValues(labels)
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	_, ok := v.values[key]
+	delete(v.values, key)
+	return ok
+}
+
+// DeletePartialMatch removes the series matching all the given labels.
+func (v *dockerCounterVec) DeletePartialMatch(labels prometheus.Labels) int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	deleted := 0
+	for key, counter := range v.values {
+		if v.matches(counter.labelValues, labels) {
+			delete(v.values, key)
+			deleted++
+		}
+	}
+	return deleted
+}
+
+func (v *dockerCounterVec) matches(labelValues []string, labels prometheus.Labels) bool {
+	for name, value := range labels {
+		index := slices.Index(v.labelNames, name)
+		if index < 0 || labelValues[index] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// Describe implements the prometheus.Collector interface.
+func (v *dockerCounterVec) Describe(ch chan<- *prometheus.Desc) {
+	ch <- v.desc
+}
+
+// Collect implements the prometheus.Collector interface.
+func (v *dockerCounterVec) Collect(ch chan<- prometheus.Metric) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	for _, counter := range v.values {
+		ch <- prometheus.MustNewConstMetric(v.desc, prometheus.CounterValue, counter.value, counter.labelValues...)
+	}
+}
@@ -0,0 +1,204 @@
+package monitors
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCPUPercent(t *testing.T) {
+	tests := []struct {
+		name     string
+		previous container.CPUStats
+		current  container.CPUStats
+		expected *float64
+	}{
+		{
+			name:     "no previous sample",
+			current:  container.CPUStats{CPUUsage: container.CPUUsage{TotalUsage: 100}, SystemUsage: 1000, OnlineCPUs: 2},
+			expected: nil,
+		},
+		{
+			name:     "half a core on two cores",
+			previous: container.CPUStats{CPUUsage: container.CPUUsage{TotalUsage: 100}, SystemUsage: 1000, OnlineCPUs: 2},
+			current:  container.CPUStats{CPUUsage: container.CPUUsage{TotalUsage: 350}, SystemUsage: 2000, OnlineCPUs: 2},
+			expected: func() *float64 { v := 50.0; return &v }(),
+		},
+		{
+			name:     "per CPU usage without online CPUs",
+			previous: container.CPUStats{CPUUsage: container.CPUUsage{TotalUsage: 0}, SystemUsage: 1000},
+			current: container.CPUStats{
+				CPUUsage:    container.CPUUsage{TotalUsage: 100, PercpuUsage: []uint64{50, 50, 0, 0}},
+				SystemUsage: 2000,
+			},
+			expected: func() *float64 { v := 40.0; return &v }(),
+		},
+		{
+			name:     "restarted container",
+			previous: container.CPUStats{CPUUsage: container.CPUUsage{TotalUsage: 500}, SystemUsage: 1000, OnlineCPUs: 1},
+			current:  container.CPUStats{CPUUsage: container.CPUUsage{TotalUsage: 100}, SystemUsage: 2000, OnlineCPUs: 1},
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := cpuPercent(tt.previous, tt.current)
+			switch {
+			case tt.expected == nil && got != nil:
+				t.Errorf("cpuPercent() = %v, want nil", *got)
+			case tt.expected != nil && got == nil:
+				t.Errorf("cpuPercent() = nil, want %v", *tt.expected)
+			case tt.expected != nil && abs(*got-*tt.expected) > 1e-9:
+				t.Errorf("cpuPercent() = %v, want %v", *got, *tt.expected)
+			}
+		})
+	}
+}
+
+func TestMemoryUsage(t *testing.T) {
+	tests := []struct {
+		name     string
+		stats    container.MemoryStats
+		expected uint64
+	}{
+		{name: "cgroup v1", stats: container.MemoryStats{Usage: 1000, Stats: map[string]uint64{"total_inactive_file": 300}}, expected: 700},
+		{name: "cgroup v2", stats: container.MemoryStats{Usage: 1000, Stats: map[string]uint64{"inactive_file": 200}}, expected: 800},
+		{name: "no page cache", stats: container.MemoryStats{Usage: 1000}, expected: 1000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := memoryUsage(tt.stats); got != tt.expected {
+				t.Errorf("memoryUsage() = %d, want %d", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDockerMonitor_Run_Stats(t *testing.T) {
+	sample := container.StatsResponse{
+		CPUStats: container.CPUStats{
+			CPUUsage:    container.CPUUsage{TotalUsage: 1000},
+			SystemUsage: 10000,
+			OnlineCPUs:  4,
+		},
+		MemoryStats: container.MemoryStats{Usage: 2048, Limit: 4096, Stats: map[string]uint64{"inactive_file": 48}},
+		Networks: map[string]container.NetworkStats{
+			"eth0": {RxBytes: 100, TxBytes: 200},
+			"eth1": {RxBytes: 10, TxBytes: 20},
+		},
+		BlkioStats: container.BlkioStats{
+			IoServiceBytesRecursive: []container.BlkioStatEntry{
+				{Op: "read", Value: 512},
+				{Op: "Write", Value: 1024},
+				{Op: "sync", Value: 1},
+			},
+		},
+	}
+	mockClient := &mockDockerClient{
+		containers: []container.Summary{{ID: "abc", Names: []string{"/nginx"}, State: "running"}},
+		stats:      map[string]container.StatsResponse{"abc": sample},
+	}
+	collector := DockerMonitorFactory{}.CreateCollector()
+	monitor := &DockerMonitor{
+		Label:         "test-container",
+		ContainerName: "nginx",
+		Stats:         true,
+		Logger:        log.Default(),
+		Metrics:       collector,
+		client:        mockClient,
+	}
+
+	if err := monitor.Run(t.Context()); err != nil {
+		t.Fatalf("Run() returned error: %v", err)
+	}
+
+	expected := map[string]float64{
+		"memory_usage": 2000,
+		"memory_limit": 4096,
+	}
+	actual := map[string]float64{
+		"memory_usage": testutil.ToFloat64(collector.MemoryUsage.WithLabelValues("test-container", "", "nginx")),
+		"memory_limit": testutil.ToFloat64(collector.MemoryLimit.WithLabelValues("test-container", "", "nginx")),
+	}
+	for name, value := range expected {
+		if actual[name] != value {
+			t.Errorf("%s: expected %v, got %v", name, value, actual[name])
+		}
+	}
+
+	// The network and block IO totals are exported as counters.
+	counters := `
+# HELP labtime_docker_container_block_io_read_bytes_total The bytes read by the Docker container from block devices.
+# TYPE labtime_docker_container_block_io_read_bytes_total counter
+labtime_docker_container_block_io_read_bytes_total{container_name="nginx",docker_host="",docker_monitor_name="test-container"} 512
+# HELP labtime_docker_container_block_io_write_bytes_total The bytes written by the Docker container to block devices.
+# TYPE labtime_docker_container_block_io_write_bytes_total counter
+labtime_docker_container_block_io_write_bytes_total{container_name="nginx",docker_host="",docker_monitor_name="test-container"} 1024
+# HELP labtime_docker_container_network_receive_bytes_total The bytes received by the Docker container on all its networks.
+# TYPE labtime_docker_container_network_receive_bytes_total counter
+labtime_docker_container_network_receive_bytes_total{container_name="nginx",docker_host="",docker_monitor_name="test-container"} 110
+# HELP labtime_docker_container_network_transmit_bytes_total The bytes sent by the Docker container on all its networks.
+# TYPE labtime_docker_container_network_transmit_bytes_total counter
+labtime_docker_container_network_transmit_bytes_total{container_name="nginx",docker_host="",docker_monitor_name="test-container"} 220
+`
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(counters),
+		"labtime_docker_container_network_receive_bytes_total",
+		"labtime_docker_container_network_transmit_bytes_total",
+		"labtime_docker_container_block_io_read_bytes_total",
+		"labtime_docker_container_block_io_write_bytes_total"); err != nil {
+		t.Errorf("Unexpected counters: %v", err)
+	}
+
+	// The CPU usage needs the sample of the previous run.
+	if count := testutil.CollectAndCount(collector, "labtime_docker_container_cpu_percent"); count != 0 {
+		t.Errorf("Expected no CPU series after the first run, got %d", count)
+	}
+	sample.CPUStats.CPUUsage.TotalUsage = 2000
+	sample.CPUStats.SystemUsage = 20000
+	mockClient.stats["abc"] = sample
+	if err := monitor.Run(t.Context()); err != nil {
+		t.Fatalf("Run() returned error: %v", err)
+	}
+	if cpu := testutil.ToFloat64(collector.CPU.WithLabelValues("test-container", "", "nginx")); cpu != 40 {
+		t.Errorf("cpu: expected 40, got %v", cpu)
+	}
+
+	// The stats are removed when the container stops.
+	mockClient.containers[0].State = "exited"
+	if err := monitor.Run(t.Context()); err != nil {
+		t.Fatalf("Run() returned error: %v", err)
+	}
+	if count := testutil.CollectAndCount(collector, "labtime_docker_container_memory_usage_bytes"); count != 0 {
+		t.Errorf("Expected no memory series for a stopped container, got %d", count)
+	}
+	if count := testutil.CollectAndCount(collector, "labtime_docker_container_network_receive_bytes_total"); count != 0 {
+		t.Errorf("Expected no network series for a stopped container, got %d", count)
+	}
+}
+
+func TestDockerMonitor_Run_StatsError(t *testing.T) {
+	monitor := &DockerMonitor{
+		Label:         "test-container",
+		ContainerName: "nginx",
+		Stats:         true,
+		Logger:        log.Default(),
+		Metrics:       DockerMonitorFactory{}.CreateCollector(),
+		client: &mockDockerClient{
+			containers: []container.Summary{{ID: "abc", Names: []string{"/nginx"}, State: "running"}},
+			statsErr:   errors.New("stats unavailable"),
+		},
+	}
+
+	if err := monitor.Run(t.Context()); err == nil {
+		t.Error("Run() should return error when the container stats fail")
+	}
+	if status := testutil.ToFloat64(monitor.Metrics.Status.WithLabelValues("test-container", "", "nginx")); status != 1 {
+		t.Errorf("Expected the status to be exported despite the stats error, got %v", status)
+	}
+}
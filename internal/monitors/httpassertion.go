@@ -0,0 +1,142 @@
+package monitors
+
+import (
+	"bytes"
+	"regexp"
+
+	"aireone.xyz/labtime/internal/yamlconfig"
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+)
+
+var ErrHTTPAssertionFailed = errors.New("http assertion failed")
+
+// HTTPAssertion represents a check performed on the HTTP response body.
+type HTTPAssertion struct {
+	Name        string
+	Contains    string
+	NotContains string
+	Regex       *regexp.Regexp
+	JSONPath    string
+	Equals      string
+	NotEquals   string
+	GreaterThan *float64
+	LessThan    *float64
+}
+
+// HTTPAssertionResult holds the outcome of an assertion.
+type HTTPAssertionResult struct {
+	Name string
+	Err  error
+}
+
+// newHTTPAssertion validates an assertion configuration and compiles it.
+func newHTTPAssertion(dto yamlconfig.HTTPAssertionDTO) (HTTPAssertion, error) {
+	if dto.Name == "" {
+		return HTTPAssertion{}, errors.New("assertion name is required")
+	}
+
+	kinds := 0
+	for _, v := range []string{dto.Contains, dto.NotContains, dto.Regex, dto.JSONPath} {
+		if v != "" {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return HTTPAssertion{}, errors.Errorf("assertion '%s' must define exactly one of contains, not_contains, regex or json_path", dto.Name)
+	}
+
+	hasComparison := dto.Equals != "" || dto.NotEquals != "" || dto.GreaterThan != nil || dto.LessThan != nil
+	if hasComparison && dto.JSONPath == "" {
+		return HTTPAssertion{}, errors.Errorf("assertion '%s' comparisons are only supported with json_path", dto.Name)
+	}
+
+	assertion := HTTPAssertion{
+		Name:        dto.Name,
+		Contains:    dto.Contains,
+		NotContains: dto.NotContains,
+		JSONPath:    dto.JSONPath,
+		Equals:      dto.Equals,
+		NotEquals:   dto.NotEquals,
+		GreaterThan: dto.GreaterThan,
+		LessThan:    dto.LessThan,
+	}
+
+	if dto.Regex != "" {
+		re, err := regexp.Compile(dto.Regex)
+		if err != nil {
+			return HTTPAssertion{}, errors.Wrapf(err, "invalid regex for assertion '%s'", dto.Name)
+		}
+		assertion.Regex = re
+	}
+
+	return assertion, nil
+}
+
+// Check evaluates the assertion against the body. It returns an error
+// wrapping ErrHTTPAssertionFailed with the failure reason.
+func (a HTTPAssertion) Check(body []byte) error {
+	switch {
+	case a.Contains != "":
+		if !bytes.Contains(body, []byte(a.Contains)) {
+			return errors.Wrapf(ErrHTTPAssertionFailed, "body does not contain %q", a.Contains)
+		}
+	case a.NotContains != "":
+		if bytes.Contains(body, []byte(a.NotContains)) {
+			return errors.Wrapf(ErrHTTPAssertionFailed, "body contains %q", a.NotContains)
+		}
+	case a.Regex != nil:
+		if !a.Regex.Match(body) {
+			return errors.Wrapf(ErrHTTPAssertionFailed, "body does not match %q", a.Regex.String())
+		}
+	case a.JSONPath != "":
+		return a.checkJSON(body)
+	}
+
+	return nil
+}
+
+func (a HTTPAssertion) checkJSON(body []byte) error {
+	if !gjson.ValidBytes(body) {
+		return errors.Wrap(ErrHTTPAssertionFailed, "body is not valid JSON")
+	}
+
+	result := gjson.GetBytes(body, a.JSONPath)
+	if !result.Exists() {
+		return errors.Wrapf(ErrHTTPAssertionFailed, "path %q not found", a.JSONPath)
+	}
+
+	if a.Equals != "" && result.String() != a.Equals {
+		return errors.Wrapf(ErrHTTPAssertionFailed, "path %q is %q, expected %q", a.JSONPath, result.String(), a.Equals)
+	}
+	if a.NotEquals != "" && result.String() == a.NotEquals {
+		return errors.Wrapf(ErrHTTPAssertionFailed, "path %q is %q", a.JSONPath, result.String())
+	}
+
+	if a.GreaterThan == nil && a.LessThan == nil {
+		return nil
+	}
+	if result.Type != gjson.Number {
+		return errors.Wrapf(ErrHTTPAssertionFailed, "path %q is not a number", a.JSONPath)
+	}
+	if a.GreaterThan != nil && result.Float() <= *a.GreaterThan {
+		return errors.Wrapf(ErrHTTPAssertionFailed, "path %q is %v, expected greater than %v", a.JSONPath, result.Float(), *a.GreaterThan)
+	}
+	if a.LessThan != nil && result.Float() >= *a.LessThan {
+		return errors.Wrapf(ErrHTTPAssertionFailed, "path %q is %v, expected less than %v", a.JSONPath, result.Float(), *a.LessThan)
+	}
+
+	return nil
+}
+
+// checkHTTPAssertions evaluates all the assertions against the body.
+func checkHTTPAssertions(assertions []HTTPAssertion, body []byte) []HTTPAssertionResult {
+	results := make([]HTTPAssertionResult, len(assertions))
+	for i, a := range assertions {
+		results[i] = HTTPAssertionResult{
+			Name: a.Name,
+			Err:  a.Check(body),
+		}
+	}
+	return results
+}
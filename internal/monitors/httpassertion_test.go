@@ -0,0 +1,119 @@
+package monitors
+
+import (
+	"errors"
+	"testing"
+
+	"aireone.xyz/labtime/internal/yamlconfig"
+)
+
+func float64Ptr(v float64) *float64 {
+	return &v
+}
+
+func TestNewHTTPAssertion(t *testing.T) {
+	tests := []struct {
+		name        string
+		dto         yamlconfig.HTTPAssertionDTO
+		expectError bool
+	}{
+		{
+			name: "contains",
+			dto:  yamlconfig.HTTPAssertionDTO{Name: "ok", Contains: "ok"},
+		},
+		{
+			name: "json path with comparison",
+			dto:  yamlconfig.HTTPAssertionDTO{Name: "status", JSONPath: "status", Equals: "up"},
+		},
+		{
+			name:        "missing name",
+			dto:         yamlconfig.HTTPAssertionDTO{Contains: "ok"},
+			expectError: true,
+		},
+		{
+			name:        "no assertion kind",
+			dto:         yamlconfig.HTTPAssertionDTO{Name: "empty"},
+			expectError: true,
+		},
+		{
+			name:        "several assertion kinds",
+			dto:         yamlconfig.HTTPAssertionDTO{Name: "both", Contains: "ok", Regex: "ok"},
+			expectError: true,
+		},
+		{
+			name:        "invalid regex",
+			dto:         yamlconfig.HTTPAssertionDTO{Name: "regex", Regex: "("},
+			expectError: true,
+		},
+		{
+			name:        "comparison without json path",
+			dto:         yamlconfig.HTTPAssertionDTO{Name: "cmp", Contains: "ok", Equals: "ok"},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newHTTPAssertion(tt.dto)
+			if tt.expectError && err == nil {
+				t.Error("Expected error but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestHTTPAssertion_Check(t *testing.T) {
+	const jsonBody = `{"status":"up","version":"1.2.3","checks":[{"name":"db","ok":true}],"uptime":3600}`
+
+	tests := []struct {
+		name    string
+		dto     yamlconfig.HTTPAssertionDTO
+		body    string
+		success bool
+	}{
+		{"contains success", yamlconfig.HTTPAssertionDTO{Contains: "healthy"}, "all healthy", true},
+		{"contains failure", yamlconfig.HTTPAssertionDTO{Contains: "healthy"}, "502 Bad Gateway", false},
+		{"not contains success", yamlconfig.HTTPAssertionDTO{NotContains: "error"}, "all good", true},
+		{"not contains failure", yamlconfig.HTTPAssertionDTO{NotContains: "error"}, "fatal error", false},
+		{"regex success", yamlconfig.HTTPAssertionDTO{Regex: `v\d+\.\d+`}, "version v1.2", true},
+		{"regex failure", yamlconfig.HTTPAssertionDTO{Regex: `v\d+\.\d+`}, "version unknown", false},
+		{"json path exists", yamlconfig.HTTPAssertionDTO{JSONPath: "version"}, jsonBody, true},
+		{"json path missing", yamlconfig.HTTPAssertionDTO{JSONPath: "missing"}, jsonBody, false},
+		{"json equals success", yamlconfig.HTTPAssertionDTO{JSONPath: "status", Equals: "up"}, jsonBody, true},
+		{"json equals failure", yamlconfig.HTTPAssertionDTO{JSONPath: "status", Equals: "down"}, jsonBody, false},
+		{"json not equals success", yamlconfig.HTTPAssertionDTO{JSONPath: "status", NotEquals: "down"}, jsonBody, true},
+		{"json not equals failure", yamlconfig.HTTPAssertionDTO{JSONPath: "status", NotEquals: "up"}, jsonBody, false},
+		{"json query", yamlconfig.HTTPAssertionDTO{JSONPath: `checks.#(name=="db").ok`, Equals: "true"}, jsonBody, true},
+		{"json greater than success", yamlconfig.HTTPAssertionDTO{JSONPath: "uptime", GreaterThan: float64Ptr(60)}, jsonBody, true},
+		{"json greater than failure", yamlconfig.HTTPAssertionDTO{JSONPath: "uptime", GreaterThan: float64Ptr(7200)}, jsonBody, false},
+		{"json less than success", yamlconfig.HTTPAssertionDTO{JSONPath: "uptime", LessThan: float64Ptr(7200)}, jsonBody, true},
+		{"json less than failure", yamlconfig.HTTPAssertionDTO{JSONPath: "uptime", LessThan: float64Ptr(60)}, jsonBody, false},
+		{"json comparison not a number", yamlconfig.HTTPAssertionDTO{JSONPath: "status", LessThan: float64Ptr(60)}, jsonBody, false},
+		{"invalid json", yamlconfig.HTTPAssertionDTO{JSONPath: "status"}, "<html></html>", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.dto.Name = tt.name
+			assertion, err := newHTTPAssertion(tt.dto)
+			if err != nil {
+				t.Fatalf("Unexpected error creating assertion: %v", err)
+			}
+
+			err = assertion.Check([]byte(tt.body))
+			if tt.success && err != nil {
+				t.Errorf("Expected success, got: %v", err)
+			}
+			if !tt.success {
+				if err == nil {
+					t.Error("Expected failure but got success")
+				} else if !errors.Is(err, ErrHTTPAssertionFailed) {
+					t.Errorf("Expected ErrHTTPAssertionFailed, got: %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package monitors
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"aireone.xyz/labtime/internal/yamlconfig"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+var ErrHTTPAuthentication = errors.New("error authenticating http request")
+
+// HTTPBasicAuth holds the credentials of the HTTP basic authentication.
+type HTTPBasicAuth struct {
+	Username string
+	Password string
+}
+
+// HTTPAuth holds the authentication settings of an HTTP target. At most one
+// method is set.
+type HTTPAuth struct {
+	BasicAuth   *HTTPBasicAuth
+	BearerToken string
+	OAuth2      *clientcredentials.Config
+}
+
+// newHTTPAuth validates the authentication settings of a target and resolves
+// its secrets.
+func newHTTPAuth(t yamlconfig.HTTPMonitorDTO) (HTTPAuth, error) {
+	methods := 0
+	if t.BasicAuth != nil {
+		methods++
+	}
+	if t.BearerToken != "" || t.BearerTokenFile != "" || t.BearerTokenEnv != "" {
+		methods++
+	}
+	if t.OAuth2 != nil {
+		methods++
+	}
+	if methods > 1 {
+		return HTTPAuth{}, errors.New("only one of basic_auth, bearer_token or oauth2 can be set")
+	}
+
+	auth := HTTPAuth{}
+
+	if t.BasicAuth != nil {
+		password, err := resolveSecret(t.BasicAuth.Password, t.BasicAuth.PasswordFile, t.BasicAuth.PasswordEnv)
+		if err != nil {
+			return HTTPAuth{}, errors.Wrap(err, "invalid basic_auth password")
+		}
+		auth.BasicAuth = &HTTPBasicAuth{
+			Username: t.BasicAuth.Username,
+			Password: password,
+		}
+	}
+
+	if t.BearerToken != "" || t.BearerTokenFile != "" || t.BearerTokenEnv != "" {
+		token, err := resolveSecret(t.BearerToken, t.BearerTokenFile, t.BearerTokenEnv)
+		if err != nil {
+			return HTTPAuth{}, errors.Wrap(err, "invalid bearer_token")
+		}
+		auth.BearerToken = token
+	}
+
+	if t.OAuth2 != nil {
+		if t.OAuth2.TokenURL == "" || t.OAuth2.ClientID == "" {
+			return HTTPAuth{}, errors.New("oauth2 token_url and client_id are required")
+		}
+		secret, err := resolveSecret(t.OAuth2.ClientSecret, t.OAuth2.ClientSecretFile, t.OAuth2.ClientSecretEnv)
+		if err != nil {
+			return HTTPAuth{}, errors.Wrap(err, "invalid oauth2 client_secret")
+		}
+		params := url.Values{}
+		for k, v := range t.OAuth2.EndpointParams {
+			params.Set(k, v)
+		}
+		auth.OAuth2 = &clientcredentials.Config{
+			ClientID:       t.OAuth2.ClientID,
+			ClientSecret:   secret,
+			TokenURL:       t.OAuth2.TokenURL,
+			Scopes:         t.OAuth2.Scopes,
+			EndpointParams: params,
+		}
+	}
+
+	return auth, nil
+}
+
+// resolveSecret returns a secret defined inline, in a file or in an
+// environment variable. At most one source can be set. Trailing newlines are
+// removed from file contents.
+func resolveSecret(value, file, env string) (string, error) {
+	sources := 0
+	for _, s := range []string{value, file, env} {
+		if s != "" {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return "", errors.New("only one of the value, file or env secret sources can be set")
+	}
+
+	switch {
+	case file != "":
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", errors.Wrapf(err, "error reading secret file %q", file)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	case env != "":
+		v, ok := os.LookupEnv(env)
+		if !ok {
+			return "", errors.Errorf("environment variable %q is not set", env)
+		}
+		return v, nil
+	default:
+		return value, nil
+	}
+}
+
+// newOAuth2TokenSource creates a token source fetching tokens with the given
+// client. Tokens are cached and refreshed when they expire.
+func newOAuth2TokenSource(config *clientcredentials.Config, client *http.Client) oauth2.TokenSource {
+	if config == nil {
+		return nil
+	}
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, client)
+	return config.TokenSource(ctx)
+}
@@ -0,0 +1,403 @@
+package monitors
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"aireone.xyz/labtime/internal/yamlconfig"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+func TestResolveSecret(t *testing.T) {
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(secretFile, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write secret file: %v", err)
+	}
+	t.Setenv("LABTIME_TEST_SECRET", "from-env")
+
+	tests := []struct {
+		name        string
+		value       string
+		file        string
+		env         string
+		expected    string
+		expectError bool
+	}{
+		{name: "inline value", value: "inline", expected: "inline"},
+		{name: "file", file: secretFile, expected: "from-file"},
+		{name: "env", env: "LABTIME_TEST_SECRET", expected: "from-env"},
+		{name: "empty", expected: ""},
+		{name: "missing file", file: filepath.Join(t.TempDir(), "missing"), expectError: true},
+		{name: "unset env", env: "LABTIME_TEST_UNSET_SECRET", expectError: true},
+		{name: "several sources", value: "inline", env: "LABTIME_TEST_SECRET", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveSecret(tt.value, tt.file, tt.env)
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("resolveSecret() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNewHTTPAuth(t *testing.T) {
+	t.Setenv("LABTIME_TEST_TOKEN", "env-token")
+
+	tests := []struct {
+		name        string
+		dto         yamlconfig.HTTPMonitorDTO
+		check       func(t *testing.T, auth HTTPAuth)
+		expectError bool
+	}{
+		{
+			name: "no authentication",
+			dto:  yamlconfig.HTTPMonitorDTO{},
+			check: func(t *testing.T, auth HTTPAuth) {
+				t.Helper()
+				if auth.BasicAuth != nil || auth.BearerToken != "" || auth.OAuth2 != nil {
+					t.Errorf("Expected no authentication, got %+v", auth)
+				}
+			},
+		},
+		{
+			name: "basic auth",
+			dto: yamlconfig.HTTPMonitorDTO{
+				BasicAuth: &yamlconfig.HTTPBasicAuthDTO{Username: "user", Password: "pass"},
+			},
+			check: func(t *testing.T, auth HTTPAuth) {
+				t.Helper()
+				if auth.BasicAuth == nil || auth.BasicAuth.Username != "user" || auth.BasicAuth.Password != "pass" {
+					t.Errorf("Unexpected basic auth %+v", auth.BasicAuth)
+				}
+			},
+		},
+		{
+			name: "bearer token from env",
+			dto:  yamlconfig.HTTPMonitorDTO{BearerTokenEnv: "LABTIME_TEST_TOKEN"},
+			check: func(t *testing.T, auth HTTPAuth) {
+				t.Helper()
+				if auth.BearerToken != "env-token" {
+					t.Errorf("Expected bearer token 'env-token', got %q", auth.BearerToken)
+				}
+			},
+		},
+		{
+			name: "oauth2",
+			dto: yamlconfig.HTTPMonitorDTO{
+				OAuth2: &yamlconfig.HTTPOAuth2DTO{
+					TokenURL:       "https://auth.example.com/token",
+					ClientID:       "labtime",
+					ClientSecret:   "secret",
+					Scopes:         []string{"health"},
+					EndpointParams: map[string]string{"audience": "api"},
+				},
+			},
+			check: func(t *testing.T, auth HTTPAuth) {
+				t.Helper()
+				if auth.OAuth2 == nil {
+					t.Fatal("Expected OAuth2 configuration")
+				}
+				if auth.OAuth2.ClientSecret != "secret" || auth.OAuth2.EndpointParams.Get("audience") != "api" {
+					t.Errorf("Unexpected OAuth2 configuration %+v", auth.OAuth2)
+				}
+			},
+		},
+		{
+			name: "oauth2 missing token url",
+			dto: yamlconfig.HTTPMonitorDTO{
+				OAuth2: &yamlconfig.HTTPOAuth2DTO{ClientID: "labtime"},
+			},
+			expectError: true,
+		},
+		{
+			name: "several methods",
+			dto: yamlconfig.HTTPMonitorDTO{
+				BasicAuth:   &yamlconfig.HTTPBasicAuthDTO{Username: "user"},
+				BearerToken: "token",
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth, err := newHTTPAuth(tt.dto)
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			tt.check(t, auth)
+		})
+	}
+}
+
+func TestHTTPMonitor_Run_RequestOptions(t *testing.T) {
+	tests := []struct {
+		name                  string
+		auth                  HTTPAuth
+		expectedAuthorization string
+	}{
+		{
+			name:                  "basic auth",
+			auth:                  HTTPAuth{BasicAuth: &HTTPBasicAuth{Username: "user", Password: "pass"}},
+			expectedAuthorization: "Basic dXNlcjpwYXNz",
+		},
+		{
+			name:                  "bearer token",
+			auth:                  HTTPAuth{BearerToken: "token"},
+			expectedAuthorization: "Bearer token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get("Authorization"); got != tt.expectedAuthorization {
+					t.Errorf("Authorization: expected %q, got %q", tt.expectedAuthorization, got)
+				}
+				if got := r.Header.Get("X-Custom"); got != "value" {
+					t.Errorf("X-Custom: expected 'value', got %q", got)
+				}
+				if r.Host != "internal.example.com" {
+					t.Errorf("Host: expected 'internal.example.com', got %q", r.Host)
+				}
+				body, _ := io.ReadAll(r.Body)
+				if string(body) != `{"ping":true}` {
+					t.Errorf("Body: expected ping payload, got %q", body)
+				}
+			}))
+			defer server.Close()
+
+			monitor := &HTTPMonitor{
+				Label:  "test-site",
+				URL:    server.URL,
+				Method: http.MethodPost,
+				Headers: map[string]string{
+					"X-Custom": "value",
+					"host":     "internal.example.com",
+				},
+				Body:    []byte(`{"ping":true}`),
+				Auth:    tt.auth,
+				Logger:  log.New(bytes.NewBuffer(nil), "", 0),
+				Client:  &http.Client{},
+				Metrics: HTTPMonitorFactory{}.CreateCollector(),
+			}
+
+			if err := monitor.Run(t.Context()); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestHTTPMonitor_Run_OAuth2(t *testing.T) {
+	var tokenRequests atomic.Int32
+
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenRequests.Add(1)
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("Failed to parse token request: %v", err)
+		}
+		if got := r.PostForm.Get("grant_type"); got != "client_credentials" {
+			t.Errorf("Expected client_credentials grant, got %q", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"oauth-token","token_type":"Bearer","expires_in":3600}`))
+	}))
+	defer tokenServer.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer oauth-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	auth, err := newHTTPAuth(yamlconfig.HTTPMonitorDTO{
+		OAuth2: &yamlconfig.HTTPOAuth2DTO{
+			TokenURL:     tokenServer.URL,
+			ClientID:     "labtime",
+			ClientSecret: "secret",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	client := &http.Client{}
+	collector := HTTPMonitorFactory{}.CreateCollector()
+	monitor := &HTTPMonitor{
+		Label:       "test-site",
+		URL:         server.URL,
+		Method:      http.MethodGet,
+		Auth:        auth,
+		Logger:      log.New(bytes.NewBuffer(nil), "", 0),
+		Client:      client,
+		Metrics:     collector,
+		TokenSource: newOAuth2TokenSource(auth.OAuth2, client),
+	}
+
+	for range 2 {
+		data, err := monitor.httpHealthCheck(t.Context())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if data.StatusCode != http.StatusOK {
+			t.Errorf("Expected status 200, got %d", data.StatusCode)
+		}
+	}
+
+	// The token must be cached between the checks
+	if got := tokenRequests.Load(); got != 1 {
+		t.Errorf("Expected 1 token request, got %d", got)
+	}
+}
+
+func TestHTTPMonitor_httpHealthCheck_OAuth2Error(t *testing.T) {
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer tokenServer.Close()
+
+	client := &http.Client{}
+	config := &clientcredentials.Config{
+		ClientID:     "labtime",
+		ClientSecret: "wrong",
+		TokenURL:     tokenServer.URL,
+	}
+	monitor := &HTTPMonitor{
+		Label:       "test-site",
+		URL:         "http://test.example.com",
+		Method:      http.MethodGet,
+		Logger:      log.New(bytes.NewBuffer(nil), "", 0),
+		Client:      client,
+		TokenSource: newOAuth2TokenSource(config, client),
+	}
+
+	_, err := monitor.httpHealthCheck(t.Context())
+	if err == nil {
+		t.Fatal("Expected error but got none")
+	}
+
+	if reason := httpErrorReason(err); reason != httpDownReasonAuthentication {
+		t.Errorf("Expected reason %s, got %s", httpDownReasonAuthentication, reason)
+	}
+
+	var retrieveErr *oauth2.RetrieveError
+	if !errors.As(err, &retrieveErr) {
+		t.Fatalf("Expected an OAuth2 retrieve error, got %v", err)
+	}
+	if retrieveErr.Response.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected token status 401, got %d", retrieveErr.Response.StatusCode)
+	}
+}
+
+func TestHTTPMonitor_httpHealthCheck_SlowOAuth2Token(t *testing.T) {
+	const tokenDelay = 300 * time.Millisecond
+
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		time.Sleep(tokenDelay)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"oauth-token","token_type":"Bearer","expires_in":3600}`))
+	}))
+	defer tokenServer.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {}))
+	defer server.Close()
+
+	client := &http.Client{}
+	config := &clientcredentials.Config{
+		ClientID:     "labtime",
+		ClientSecret: "secret",
+		TokenURL:     tokenServer.URL,
+	}
+	monitor := &HTTPMonitor{
+		Label:       "test-site",
+		URL:         server.URL,
+		Method:      http.MethodGet,
+		Logger:      log.New(bytes.NewBuffer(nil), "", 0),
+		Client:      client,
+		TokenSource: newOAuth2TokenSource(config, client),
+	}
+
+	data, err := monitor.httpHealthCheck(t.Context())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The token fetch must not be part of the response time
+	if data.Duration >= tokenDelay {
+		t.Errorf("Expected duration below %v, got %v", tokenDelay, data.Duration)
+	}
+}
+
+func TestHTTPTargetProvider_GetTargets_RequestOptions(t *testing.T) {
+	bodyFile := filepath.Join(t.TempDir(), "body.json")
+	if err := os.WriteFile(bodyFile, []byte(`{"ping":true}`), 0o600); err != nil {
+		t.Fatalf("Failed to write body file: %v", err)
+	}
+
+	provider := HTTPTargetProvider{}
+
+	targets, err := provider.GetTargets(&yamlconfig.YamlConfig{
+		HTTPStatusCode: []yamlconfig.HTTPMonitorDTO{
+			{
+				URL:         "https://example.com",
+				Method:      "POST",
+				Headers:     map[string]string{"Content-Type": "application/json"},
+				BodyFile:    bodyFile,
+				BearerToken: "token",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("GetTargets() returned unexpected error: %v", err)
+	}
+
+	if string(targets[0].Body) != `{"ping":true}` {
+		t.Errorf("Body: unexpected value %q", targets[0].Body)
+	}
+	if targets[0].Headers["Content-Type"] != "application/json" {
+		t.Errorf("Headers: unexpected value %v", targets[0].Headers)
+	}
+	if targets[0].Auth.BearerToken != "token" {
+		t.Errorf("BearerToken: unexpected value %q", targets[0].Auth.BearerToken)
+	}
+
+	invalid := []yamlconfig.HTTPMonitorDTO{
+		{URL: "https://example.com", Body: "inline", BodyFile: bodyFile},
+		{URL: "https://example.com", BodyFile: filepath.Join(t.TempDir(), "missing")},
+		{URL: "https://example.com", BearerToken: "token", OAuth2: &yamlconfig.HTTPOAuth2DTO{}},
+	}
+	for _, dto := range invalid {
+		if _, err := provider.GetTargets(&yamlconfig.YamlConfig{HTTPStatusCode: []yamlconfig.HTTPMonitorDTO{dto}}); err == nil {
+			t.Errorf("Expected error for %+v but got none", dto)
+		}
+	}
+}
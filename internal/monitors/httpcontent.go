@@ -0,0 +1,47 @@
+package monitors
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+
+	"aireone.xyz/labtime/internal/yamlconfig"
+	"github.com/pkg/errors"
+)
+
+// HTTPContentChange holds the settings of the response body change
+// detection.
+type HTTPContentChange struct {
+	// Ignore matches the volatile parts of the body (timestamps, nonces...)
+	// removed before hashing.
+	Ignore []*regexp.Regexp
+}
+
+// newHTTPContentChange validates the content change detection settings. It
+// returns nil when the detection is disabled.
+func newHTTPContentChange(dto *yamlconfig.HTTPContentChangeDTO) (*HTTPContentChange, error) {
+	if dto == nil {
+		return nil, nil //nolint:nilnil // disabled detection is not an error
+	}
+
+	contentChange := &HTTPContentChange{}
+	for _, pattern := range dto.Ignore {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid content_change ignore pattern %q", pattern)
+		}
+		contentChange.Ignore = append(contentChange.Ignore, re)
+	}
+
+	return contentChange, nil
+}
+
+// Hash returns the SHA-256 hex digest of the body without its volatile
+// parts.
+func (c *HTTPContentChange) Hash(body []byte) string {
+	for _, re := range c.Ignore {
+		body = re.ReplaceAll(body, nil)
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
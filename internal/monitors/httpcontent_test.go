@@ -0,0 +1,140 @@
+package monitors
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"aireone.xyz/labtime/internal/yamlconfig"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestNewHTTPContentChange(t *testing.T) {
+	contentChange, err := newHTTPContentChange(nil)
+	if err != nil || contentChange != nil {
+		t.Errorf("Expected disabled detection, got %+v (%v)", contentChange, err)
+	}
+
+	contentChange, err = newHTTPContentChange(&yamlconfig.HTTPContentChangeDTO{Ignore: []string{`\d+`}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(contentChange.Ignore) != 1 {
+		t.Errorf("Expected 1 ignore pattern, got %d", len(contentChange.Ignore))
+	}
+
+	if _, err := newHTTPContentChange(&yamlconfig.HTTPContentChangeDTO{Ignore: []string{"("}}); err == nil {
+		t.Error("Expected error for invalid pattern but got none")
+	}
+}
+
+func TestHTTPContentChange_Hash(t *testing.T) {
+	plain := &HTTPContentChange{}
+	if plain.Hash([]byte("a")) == plain.Hash([]byte("b")) {
+		t.Error("Expected different hashes for different bodies")
+	}
+	if plain.Hash([]byte("a")) != plain.Hash([]byte("a")) {
+		t.Error("Expected identical hashes for identical bodies")
+	}
+
+	contentChange, err := newHTTPContentChange(&yamlconfig.HTTPContentChangeDTO{
+		Ignore: []string{`generated at \S+`, `nonce="[^"]*"`},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	first := contentChange.Hash([]byte(`<p nonce="abc">ok</p> generated at 10:00`))
+	second := contentChange.Hash([]byte(`<p nonce="def">ok</p> generated at 10:01`))
+	if first != second {
+		t.Error("Expected volatile parts to be ignored")
+	}
+	if first == contentChange.Hash([]byte(`<p nonce="abc">ko</p> generated at 10:00`)) {
+		t.Error("Expected a content change to change the hash")
+	}
+}
+
+func TestHTTPTargetProvider_GetTargets_ContentChangeMethod(t *testing.T) {
+	provider := HTTPTargetProvider{}
+	contentChange := &yamlconfig.HTTPContentChangeDTO{}
+
+	targets, err := provider.GetTargets(&yamlconfig.YamlConfig{
+		HTTPStatusCode: []yamlconfig.HTTPMonitorDTO{{URL: "https://example.com", ContentChange: contentChange}},
+	})
+	if err != nil {
+		t.Fatalf("GetTargets() returned unexpected error: %v", err)
+	}
+	if targets[0].Method != http.MethodGet {
+		t.Errorf("Expected GET by default with content_change, got %s", targets[0].Method)
+	}
+
+	_, err = provider.GetTargets(&yamlconfig.YamlConfig{
+		HTTPStatusCode: []yamlconfig.HTTPMonitorDTO{
+			{URL: "https://example.com", Method: http.MethodHead, ContentChange: contentChange},
+		},
+	})
+	if err == nil {
+		t.Error("Expected error for content_change with HEAD but got none")
+	}
+}
+
+func TestHTTPMonitor_Run_ContentChange(t *testing.T) {
+	var body atomic.Value
+	body.Store("version 1")
+	var status atomic.Int32
+	status.Store(http.StatusOK)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(int(status.Load()))
+		_, _ = w.Write([]byte(body.Load().(string)))
+	}))
+	defer server.Close()
+
+	collector := HTTPMonitorFactory{}.CreateCollector()
+	monitor := &HTTPMonitor{
+		Label:         "status-page",
+		URL:           server.URL,
+		Method:        http.MethodGet,
+		ContentChange: &HTTPContentChange{},
+		Logger:        log.New(bytes.NewBuffer(nil), "", 0),
+		Client:        &http.Client{},
+		Metrics:       collector,
+	}
+	labels := prometheus.Labels{
+		"http_monitor_site_name": "status-page",
+		"http_site_url":          server.URL,
+		"address":                "",
+		"proxy":                  "",
+	}
+
+	run := func(expectedChanges float64) {
+		t.Helper()
+		if err := monitor.Run(t.Context()); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got := testutil.ToFloat64(collector.ContentChanges.With(labels)); got != expectedChanges {
+			t.Errorf("Expected %f changes, got %f", expectedChanges, got)
+		}
+		if got := testutil.ToFloat64(collector.ContentChanged.With(labels)); got == 0 {
+			t.Error("Expected the change timestamp to be set")
+		}
+	}
+
+	// The first content seen is not a change.
+	run(0)
+	run(0)
+
+	body.Store("version 2")
+	run(1)
+
+	// Error pages are ignored.
+	status.Store(http.StatusInternalServerError)
+	body.Store("oops")
+	run(1)
+
+	status.Store(http.StatusOK)
+	body.Store("version 2")
+	run(1)
+}
@@ -1,22 +1,61 @@
 package monitors
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
 	"log"
+	"net"
 	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
 
 	"aireone.xyz/labtime/internal/middlewares"
 	"aireone.xyz/labtime/internal/yamlconfig"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// defaultHTTPBodyMaxSize is the default maximum number of bytes read from
+	// a response body.
+	defaultHTTPBodyMaxSize = 1 << 20
+	// defaultHTTPTimeout is the default request timeout in seconds.
+	defaultHTTPTimeout = 10
+	// defaultHTTPMaxRedirects is the default maximum number of redirects.
+	defaultHTTPMaxRedirects = 10
 )
 
 // HTTPTarget represents an HTTP monitoring target.
 type HTTPTarget struct {
-	Name     string `yaml:"name"`
-	URL      string `yaml:"url"`
-	Method   string `yaml:"method"`
-	Interval int    `yaml:"interval,omitempty"`
+	Name              string `yaml:"name"`
+	URL               string `yaml:"url"`
+	SocketPath        string `yaml:"socket_path,omitempty"`
+	RequestURL        string
+	Method            string `yaml:"method"`
+	Interval          int    `yaml:"interval,omitempty"`
+	Timeout           int    `yaml:"timeout,omitempty"`
+	FollowRedirects   bool   `yaml:"follow_redirects,omitempty"`
+	MaxRedirects      int    `yaml:"max_redirects,omitempty"`
+	DisableKeepAlives bool   `yaml:"disable_keepalives,omitempty"`
+	IPProtocol        string `yaml:"ip_protocol,omitempty"`
+	ProbeAllAddresses bool   `yaml:"probe_all_addresses,omitempty"`
+	Proxy             *HTTPProxy
+	ExpectedStatus    HTTPStatusMatcher `yaml:"expected_status,omitempty"`
+	Headers           map[string]string `yaml:"headers,omitempty"`
+	Body              []byte            `yaml:"body,omitempty"`
+	Auth              HTTPAuth
+	TLSConfig         *tls.Config
+	BodyMaxSize       int64              `yaml:"body_max_size,omitempty"`
+	Assertions        []HTTPAssertion    `yaml:"assertions,omitempty"`
+	ContentChange     *HTTPContentChange `yaml:"content_change,omitempty"`
 }
 
 // GetName implements the Target interface.
@@ -32,25 +71,116 @@ func (h HTTPTarget) GetInterval() int {
 // HTTPMonitorFactory implements MonitorFactory for HTTP monitoring.
 type HTTPMonitorFactory struct{}
 
-// CreateCollector creates a Prometheus GaugeVec for HTTP monitoring.
-func (h HTTPMonitorFactory) CreateCollector() *prometheus.GaugeVec {
-	return prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "labtime_http_site_status_code",
-		Help: "The status code of the site.",
-	}, []string{"http_monitor_site_name", "http_site_url"})
+// HTTPCollector groups the Prometheus metrics exported by the HTTP monitors.
+type HTTPCollector struct {
+	StatusCode       *prometheus.GaugeVec
+	Up               *prometheus.GaugeVec
+	ResponseTime     *prometheus.HistogramVec
+	PhaseDuration    *prometheus.GaugeVec
+	AssertionSuccess *prometheus.GaugeVec
+	Redirects        *prometheus.GaugeVec
+	FinalURL         *prometheus.GaugeVec
+	ContentChanged   *prometheus.GaugeVec
+	ContentChanges   *prometheus.CounterVec
+
+	multiCollector
+}
+
+// DeletePartialMatch deletes the series matching the labels from all the
+// metrics.
+func (c *HTTPCollector) DeletePartialMatch(labels prometheus.Labels) {
+	for _, vec := range []*prometheus.MetricVec{
+		c.StatusCode.MetricVec,
+		c.Up.MetricVec,
+		c.ResponseTime.MetricVec,
+		c.PhaseDuration.MetricVec,
+		c.AssertionSuccess.MetricVec,
+		c.Redirects.MetricVec,
+		c.FinalURL.MetricVec,
+		c.ContentChanged.MetricVec,
+		c.ContentChanges.MetricVec,
+	} {
+		vec.DeletePartialMatch(labels)
+	}
+}
+
+// CreateCollector creates the Prometheus collectors for HTTP monitoring.
+func (h HTTPMonitorFactory) CreateCollector() *HTTPCollector {
+	labels := []string{"http_monitor_site_name", "http_site_url", "address", "proxy"}
+	c := &HTTPCollector{
+		StatusCode: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "labtime_http_site_status_code",
+			Help: "The status code of the site.",
+		}, labels),
+		Up: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "labtime_http_up",
+			Help: "Whether the site is up (1 = up, 0 = down). The reason label explains why the site is down.",
+		}, append(labels, "reason")),
+		ResponseTime: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "labtime_http_response_duration_seconds",
+			Help:    "The duration (in second) of the HTTP request, from the request creation to the end of the body transfer.",
+			Buckets: prometheus.DefBuckets,
+		}, labels),
+		PhaseDuration: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "labtime_http_phase_duration_seconds",
+			Help: "The duration (in second) of each phase of the last HTTP request (dns, connect, tls, first_byte, transfer).",
+		}, append(labels, "phase")),
+		AssertionSuccess: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "labtime_http_assertion_success",
+			Help: "The result of the response body assertion (1 = success, 0 = failure).",
+		}, append(labels, "assertion")),
+		Redirects: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "labtime_http_redirects",
+			Help: "The number of redirects followed by the last HTTP request.",
+		}, labels),
+		FinalURL: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "labtime_http_final_url_info",
+			Help: "The URL of the last HTTP response, after following the redirects. The value is always 1.",
+		}, append(labels, "final_url")),
+		ContentChanged: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "labtime_http_content_hash_changed_timestamp",
+			Help: "The timestamp (in seconds since epoch) at which the current response body hash was first seen.",
+		}, labels),
+		ContentChanges: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "labtime_http_content_changes_total",
+			Help: "The number of response body changes detected since labtime started.",
+		}, labels),
+	}
+	c.multiCollector = multiCollector{
+		c.StatusCode, c.Up, c.ResponseTime, c.PhaseDuration, c.AssertionSuccess, c.Redirects, c.FinalURL,
+		c.ContentChanged, c.ContentChanges,
+	}
+	return c
 }
 
 // CreateMonitor creates an HTTP monitor instance.
-func (h HTTPMonitorFactory) CreateMonitor(target HTTPTarget, collector *prometheus.GaugeVec, logger *log.Logger) Job {
+func (h HTTPMonitorFactory) CreateMonitor(target HTTPTarget, collector *HTTPCollector, logger *log.Logger) Job {
+	client := &http.Client{
+		Transport:     middlewares.NewLoggerMiddleware(logger, newHTTPTransport(target)),
+		CheckRedirect: newHTTPRedirectPolicy(target.FollowRedirects, target.MaxRedirects),
+		Timeout:       time.Duration(target.Timeout) * time.Second,
+	}
+
 	return &HTTPMonitor{
-		Label:                 target.Name,
-		URL:                   target.URL,
-		Method:                target.Method,
-		Logger:                logger,
-		SiteStatusCodeMonitor: collector,
-		Client: &http.Client{
-			Transport: middlewares.NewLoggerMiddleware(logger, http.DefaultTransport),
-		},
+		Label:          target.Name,
+		URL:            target.URL,
+		RequestURL:     target.RequestURL,
+		Proxy:          target.Proxy.Label(target.URL),
+		Method:         target.Method,
+		ExpectedStatus: target.ExpectedStatus,
+		Headers:        target.Headers,
+		Body:           target.Body,
+		Auth:           target.Auth,
+		BodyMaxSize:    target.BodyMaxSize,
+		Assertions:     target.Assertions,
+		ContentChange:  target.ContentChange,
+		IPProtocol:     target.IPProtocol,
+		ProbeAll:       target.ProbeAllAddresses,
+		Logger:         logger,
+		Metrics:        collector,
+		Client:         client,
+		LookupIP:       net.DefaultResolver.LookupIP,
+		TokenSource:    newOAuth2TokenSource(target.Auth.OAuth2, client),
 	}
 }
 
@@ -69,17 +199,123 @@ func (h HTTPTargetProvider) GetTargets(config *yamlconfig.YamlConfig) ([]HTTPTar
 		if interval == 0 {
 			interval = 60
 		}
+		// The assertions and the content change detection need a response
+		// body, HEAD responses have none.
+		readsBody := len(t.Assertions) > 0 || t.ContentChange != nil
 		method := t.Method
 		if method == "" {
 			method = http.MethodHead
+			if readsBody {
+				method = http.MethodGet
+			}
 		} else if !isValidHTTPMethod(method) {
 			return nil, errors.Wrapf(errors.New("invalid HTTP method"), "invalid method '%s' for target '%s'", method, name)
 		}
+		if readsBody && method == http.MethodHead {
+			return nil, errors.Errorf("assertions and content_change require a method returning a body for target '%s'", name)
+		}
+		timeout := t.Timeout
+		if timeout == 0 {
+			timeout = defaultHTTPTimeout
+		} else if timeout < 0 {
+			return nil, errors.Errorf("invalid timeout %d for target '%s'", timeout, name)
+		}
+		followRedirects := true
+		if t.FollowRedirects != nil {
+			followRedirects = *t.FollowRedirects
+		}
+		maxRedirects := t.MaxRedirects
+		if maxRedirects == 0 {
+			maxRedirects = defaultHTTPMaxRedirects
+		} else if maxRedirects < 0 {
+			return nil, errors.Errorf("invalid max_redirects %d for target '%s'", maxRedirects, name)
+		}
+		var socketPath, requestURL string
+		if strings.HasPrefix(t.URL, unixSocketScheme) {
+			var err error
+			socketPath, requestURL, err = parseUnixSocketURL(t.URL)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid url for target '%s'", name)
+			}
+			if t.ProbeAllAddresses || t.IPProtocol != "" || t.ProxyURL != "" {
+				return nil, errors.Errorf("ip_protocol, probe_all_addresses and proxy_url are not supported with unix sockets for target '%s'", name)
+			}
+		}
+		proxy, err := newHTTPProxy(t.ProxyURL, t.NoProxy)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid proxy for target '%s'", name)
+		}
+		if proxy != nil && t.ProbeAllAddresses {
+			return nil, errors.Errorf("probe_all_addresses is not supported with proxy_url for target '%s'", name)
+		}
+		ipProtocol := t.IPProtocol
+		if ipProtocol == "" {
+			ipProtocol = ipProtocolBoth
+		} else if !isValidIPProtocol(ipProtocol) {
+			return nil, errors.Errorf("invalid ip_protocol '%s' for target '%s'", ipProtocol, name)
+		}
+		expectedStatus, err := parseHTTPStatusMatcher(t.ExpectedStatus)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid expected_status for target '%s'", name)
+		}
+		body := []byte(t.Body)
+		if t.BodyFile != "" {
+			if t.Body != "" {
+				return nil, errors.Errorf("only one of body or body_file can be set for target '%s'", name)
+			}
+			body, err = os.ReadFile(t.BodyFile)
+			if err != nil {
+				return nil, errors.Wrapf(err, "error reading body_file for target '%s'", name)
+			}
+		}
+		auth, err := newHTTPAuth(t)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid authentication for target '%s'", name)
+		}
+		tlsConfig, err := newHTTPTLSConfig(t.TLS)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid tls settings for target '%s'", name)
+		}
+		bodyMaxSize := t.BodyMaxSize
+		if bodyMaxSize == 0 {
+			bodyMaxSize = defaultHTTPBodyMaxSize
+		} else if bodyMaxSize < 0 {
+			return nil, errors.Errorf("invalid body_max_size %d for target '%s'", bodyMaxSize, name)
+		}
+		assertions := make([]HTTPAssertion, len(t.Assertions))
+		for j, a := range t.Assertions {
+			assertion, err := newHTTPAssertion(a)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid assertion for target '%s'", name)
+			}
+			assertions[j] = assertion
+		}
+		contentChange, err := newHTTPContentChange(t.ContentChange)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid content_change for target '%s'", name)
+		}
 		targets[i] = HTTPTarget{
-			Name:     name,
-			URL:      t.URL,
-			Method:   method,
-			Interval: interval,
+			Name:              name,
+			URL:               t.URL,
+			SocketPath:        socketPath,
+			RequestURL:        requestURL,
+			Method:            method,
+			Interval:          interval,
+			Timeout:           timeout,
+			FollowRedirects:   followRedirects,
+			MaxRedirects:      maxRedirects,
+			DisableKeepAlives: t.DisableKeepAlives,
+			IPProtocol:        ipProtocol,
+			ProbeAllAddresses: t.ProbeAllAddresses,
+			Proxy:             proxy,
+			ExpectedStatus:    expectedStatus,
+			Headers:           t.Headers,
+			Body:              body,
+			Auth:              auth,
+			TLSConfig:         tlsConfig,
+			BodyMaxSize:       bodyMaxSize,
+			Assertions:        assertions,
+			ContentChange:     contentChange,
 		}
 	}
 	return targets, nil
@@ -107,16 +343,48 @@ type HTTPClient interface {
 	Do(req *http.Request) (*http.Response, error)
 }
 
+// LookupIPFunc resolves the IP addresses of a host for the given network
+// ("ip", "ip4" or "ip6").
+type LookupIPFunc func(ctx context.Context, network, host string) ([]net.IP, error)
+
 type HTTPMonitor struct {
-	Label  string
-	URL    string
-	Method string
+	Label          string
+	URL            string
+	RequestURL     string
+	Proxy          string
+	Method         string
+	ExpectedStatus HTTPStatusMatcher
+	Headers        map[string]string
+	Body           []byte
+	Auth           HTTPAuth
+	BodyMaxSize    int64
+	Assertions     []HTTPAssertion
+	// ContentChange enables the response body change detection when set.
+	ContentChange *HTTPContentChange
+
+	// IPProtocol restricts the resolved addresses (ipv4, ipv6 or both) when
+	// ProbeAll is set.
+	IPProtocol string
+	// ProbeAll probes each resolved address of the URL host individually.
+	ProbeAll bool
 
 	Logger *log.Logger
 
-	SiteStatusCodeMonitor *prometheus.GaugeVec
+	Metrics *HTTPCollector
 
-	Client HTTPClient
+	Client   HTTPClient
+	LookupIP LookupIPFunc
+
+	mu sync.Mutex
+	// addresses are the addresses probed during the last run, used to remove
+	// the series of addresses that are no longer resolved.
+	addresses []string
+	// contentHashes are the last response body hashes, by address.
+	contentHashes map[string]string
+
+	// TokenSource provides the OAuth2 tokens when the OAuth2 authentication
+	// is configured.
+	TokenSource oauth2.TokenSource
 }
 
 func (h *HTTPMonitor) ID() string {
@@ -124,8 +392,38 @@ func (h *HTTPMonitor) ID() string {
 }
 
 func (h *HTTPMonitor) Run(ctx context.Context) error {
+	if !h.ProbeAll {
+		return h.probe(ctx)
+	}
+
+	addresses, err := h.resolveAddresses(ctx)
+	if err != nil {
+		h.forgetAddresses(nil)
+		h.pushFailureToPrometheus("", err)
+		return errors.Wrap(err, "error resolving http target addresses")
+	}
+	h.forgetAddresses(addresses)
+
+	host := h.host()
+	var failures []string
+	for _, address := range addresses {
+		if err := h.probe(withDialAddress(ctx, host, address)); err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+	if len(failures) > 0 {
+		return errors.Errorf("%d of %d addresses failed: %s", len(failures), len(addresses), strings.Join(failures, "; "))
+	}
+
+	return nil
+}
+
+// probe runs a single health check and pushes its result.
+func (h *HTTPMonitor) probe(ctx context.Context) error {
 	d, err := h.httpHealthCheck(ctx)
 	if err != nil {
+		address, _ := dialAddressFrom(ctx)
+		h.pushFailureToPrometheus(address.IP, err)
 		return errors.Wrap(err, "error running http health check")
 	}
 
@@ -134,16 +432,114 @@ func (h *HTTPMonitor) Run(ctx context.Context) error {
 	return nil
 }
 
+// requestURL returns the URL of the requests.
+func (h *HTTPMonitor) requestURL() string {
+	if h.RequestURL != "" {
+		return h.RequestURL
+	}
+	return h.URL
+}
+
+// host returns the host name of the target URL.
+func (h *HTTPMonitor) host() string {
+	u, err := url.Parse(h.URL)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+// resolveAddresses returns the IP addresses of the target host, filtered by
+// the IP protocol.
+func (h *HTTPMonitor) resolveAddresses(ctx context.Context) ([]string, error) {
+	host := h.host()
+	if host == "" {
+		return nil, errors.Errorf("invalid URL %q", h.URL)
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		return []string{ip.String()}, nil
+	}
+
+	ips, err := h.LookupIP(ctx, lookupNetwork(h.IPProtocol), host)
+	if err != nil {
+		return nil, err
+	}
+	if len(ips) == 0 {
+		return nil, &net.DNSError{Err: "no address found", Name: host, IsNotFound: true}
+	}
+
+	addresses := make([]string, len(ips))
+	for i, ip := range ips {
+		addresses[i] = ip.String()
+	}
+	return addresses, nil
+}
+
+// forgetAddresses removes the series of the addresses probed during the
+// previous run that are not part of the current addresses.
+func (h *HTTPMonitor) forgetAddresses(current []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, previous := range h.addresses {
+		found := false
+		for _, address := range current {
+			if address == previous {
+				found = true
+				break
+			}
+		}
+		if !found {
+			h.Metrics.DeletePartialMatch(h.labels(previous))
+			delete(h.contentHashes, previous)
+		}
+	}
+
+	// A failed resolution is reported without address.
+	h.addresses = append([]string{""}, current...)
+}
+
 type HTTPHealthCheckerData struct {
+	Address    string
 	StatusCode int
+	Duration   time.Duration
+	Timings    HTTPPhaseTimings
+	Assertions []HTTPAssertionResult
+	FinalURL   string
+	Redirects  int
+	// ContentHash is the hash of the response body, set when the content
+	// change detection is enabled.
+	ContentHash string
 }
 
 func (h *HTTPMonitor) httpHealthCheck(ctx context.Context) (*HTTPHealthCheckerData, error) {
-	req, err := http.NewRequestWithContext(ctx, h.Method, h.URL, http.NoBody)
+	var reqBody io.Reader = http.NoBody
+	if len(h.Body) > 0 {
+		reqBody = bytes.NewReader(h.Body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, h.Method, h.requestURL(), reqBody)
 	if err != nil {
 		return nil, errors.Wrap(err, "error creating http request")
 	}
-	req = req.WithContext(ctx)
+
+	for k, v := range h.Headers {
+		if http.CanonicalHeaderKey(k) == "Host" {
+			req.Host = v
+			continue
+		}
+		req.Header.Set(k, v)
+	}
+
+	if err := h.authenticate(req); err != nil {
+		return nil, err
+	}
+
+	// The timer starts after the authentication, fetching an OAuth2 token is
+	// not part of the response time.
+	timer := newHTTPTimer()
+	req = req.WithContext(httptrace.WithClientTrace(ctx, timer.clientTrace()))
 
 	resp, err := h.Client.Do(req)
 	if err != nil {
@@ -151,17 +547,187 @@ func (h *HTTPMonitor) httpHealthCheck(ctx context.Context) (*HTTPHealthCheckerDa
 	}
 	defer resp.Body.Close()
 
-	return &HTTPHealthCheckerData{
+	// Read the body (up to the size limit) so the transfer time is part of the
+	// measure and the assertions can be evaluated.
+	maxSize := h.BodyMaxSize
+	if maxSize <= 0 {
+		maxSize = defaultHTTPBodyMaxSize
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSize))
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading http response body")
+	}
+	timer.done()
+
+	finalURL, redirects := redirectsOf(resp)
+	// Requests sent over a Unix domain socket report the target URL rather
+	// than the placeholder request URL.
+	if finalURL == "" || (h.RequestURL != "" && redirects == 0) {
+		finalURL = h.URL
+	}
+
+	address, _ := dialAddressFrom(ctx)
+
+	data := &HTTPHealthCheckerData{
+		Address:    address.IP,
 		StatusCode: resp.StatusCode,
-	}, nil
+		Duration:   timer.total(),
+		Timings:    timer.timings(),
+		Assertions: checkHTTPAssertions(h.Assertions, body),
+		FinalURL:   finalURL,
+		Redirects:  redirects,
+	}
+
+	if h.ContentChange != nil {
+		data.ContentHash = h.ContentChange.Hash(body)
+	}
+
+	return data, nil
+}
+
+// authenticate adds the configured credentials to the request.
+func (h *HTTPMonitor) authenticate(req *http.Request) error {
+	switch {
+	case h.TokenSource != nil:
+		token, err := h.TokenSource.Token()
+		if err != nil {
+			// Both errors are kept in the chain, the token error may be an
+			// *oauth2.RetrieveError.
+			return fmt.Errorf("%w: %w", ErrHTTPAuthentication, err)
+		}
+		token.SetAuthHeader(req)
+	case h.Auth.BasicAuth != nil:
+		req.SetBasicAuth(h.Auth.BasicAuth.Username, h.Auth.BasicAuth.Password)
+	case h.Auth.BearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+h.Auth.BearerToken)
+	}
+
+	return nil
+}
+
+// labels returns the labels identifying the target. The address is empty
+// unless each resolved address is probed individually, the proxy is empty
+// unless the target is checked through its configured proxy.
+func (h *HTTPMonitor) labels(address string) prometheus.Labels {
+	return prometheus.Labels{
+		"http_monitor_site_name": h.Label,
+		"http_site_url":          h.URL,
+		"address":                address,
+		"proxy":                  h.Proxy,
+	}
+}
+
+// logName returns the name of the target used in the logs.
+func (h *HTTPMonitor) logName(address string) string {
+	if address == "" {
+		return h.Label
+	}
+	return h.Label + " (" + address + ")"
+}
+
+// setUp updates the up gauge. Previous series of the target are removed so a
+// single reason is exported at a time.
+func (h *HTTPMonitor) setUp(address string, up bool, reason string) {
+	labels := h.labels(address)
+	h.Metrics.Up.DeletePartialMatch(labels)
+
+	value := 0.0
+	if up {
+		value = 1
+	}
+	h.Metrics.Up.MustCurryWith(labels).WithLabelValues(reason).Set(value)
+}
+
+// pushFailureToPrometheus reports a target as down when the request failed.
+func (h *HTTPMonitor) pushFailureToPrometheus(address string, err error) {
+	reason := httpErrorReason(err)
+	h.Logger.Printf("HTTP health check for %s failed (%s): %v", h.logName(address), reason, err)
+
+	h.Metrics.StatusCode.With(h.labels(address)).Set(0)
+	h.setUp(address, false, reason)
 }
 
 func (h *HTTPMonitor) pushToPrometheus(d *HTTPHealthCheckerData) {
-	h.Logger.Printf("HTTP health check for %s: status code %d", h.Label, d.StatusCode)
-	h.SiteStatusCodeMonitor.
-		With(prometheus.Labels{
-			"http_monitor_site_name": h.Label,
-			"http_site_url":          h.URL,
-		}).
-		Set(float64(d.StatusCode))
+	name := h.logName(d.Address)
+	h.Logger.Printf("HTTP health check for %s: status code %d", name, d.StatusCode)
+	labels := h.labels(d.Address)
+
+	h.Metrics.StatusCode.With(labels).Set(float64(d.StatusCode))
+	h.Metrics.ResponseTime.With(labels).Observe(d.Duration.Seconds())
+
+	phases := map[string]time.Duration{
+		"dns":        d.Timings.DNSLookup,
+		"connect":    d.Timings.TCPConnect,
+		"tls":        d.Timings.TLSHandshake,
+		"first_byte": d.Timings.FirstByte,
+		"transfer":   d.Timings.Transfer,
+	}
+	for phase, duration := range phases {
+		h.Metrics.PhaseDuration.
+			MustCurryWith(labels).
+			WithLabelValues(phase).
+			Set(duration.Seconds())
+	}
+
+	if d.Redirects > 0 {
+		h.Logger.Printf("HTTP health check for %s: %d redirects to %s", name, d.Redirects, d.FinalURL)
+	}
+	h.Metrics.Redirects.With(labels).Set(float64(d.Redirects))
+	h.Metrics.FinalURL.DeletePartialMatch(labels)
+	h.Metrics.FinalURL.MustCurryWith(labels).WithLabelValues(d.FinalURL).Set(1)
+
+	assertionsPassed := true
+	for _, a := range d.Assertions {
+		success := 1.0
+		if a.Err != nil {
+			success = 0
+			assertionsPassed = false
+			h.Logger.Printf("HTTP assertion '%s' failed for %s: %v", a.Name, name, a.Err)
+		}
+		h.Metrics.AssertionSuccess.
+			MustCurryWith(labels).
+			WithLabelValues(a.Name).
+			Set(success)
+	}
+
+	// Error pages are not considered as content changes.
+	if d.ContentHash != "" && h.ExpectedStatus.Match(d.StatusCode) {
+		h.recordContentHash(d.Address, d.ContentHash)
+	}
+
+	switch {
+	case !h.ExpectedStatus.Match(d.StatusCode):
+		h.Logger.Printf("HTTP health check for %s: unexpected status code %d", name, d.StatusCode)
+		h.setUp(d.Address, false, httpDownReasonUnexpectedStatus)
+	case !assertionsPassed:
+		h.setUp(d.Address, false, httpDownReasonAssertionFailed)
+	default:
+		h.setUp(d.Address, true, "")
+	}
+}
+
+// recordContentHash compares the response body hash to the previous one. The
+// first hash seen sets the change timestamp without counting a change.
+func (h *HTTPMonitor) recordContentHash(address, hash string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	previous, seen := h.contentHashes[address]
+	if seen && previous == hash {
+		return
+	}
+
+	if h.contentHashes == nil {
+		h.contentHashes = make(map[string]string)
+	}
+	h.contentHashes[address] = hash
+
+	labels := h.labels(address)
+	if seen {
+		h.Logger.Printf("HTTP health check for %s: content changed", h.logName(address))
+		h.Metrics.ContentChanges.With(labels).Inc()
+	} else {
+		h.Metrics.ContentChanges.With(labels).Add(0)
+	}
+	h.Metrics.ContentChanged.With(labels).SetToCurrentTime()
 }
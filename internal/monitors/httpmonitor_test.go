@@ -5,10 +5,14 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"strings"
+	"syscall"
 	"testing"
+	"time"
 
 	"aireone.xyz/labtime/internal/yamlconfig"
 	"github.com/prometheus/client_golang/prometheus"
@@ -77,9 +81,11 @@ func TestHTTPMonitorFactory_CreateCollector(t *testing.T) {
 	}
 
 	// Test that the collector accepts the expected label structure without panicking
-	gauge := collector.With(prometheus.Labels{
+	gauge := collector.StatusCode.With(prometheus.Labels{
 		"http_monitor_site_name": "test-site",
 		"http_site_url":          "http://test.com",
+		"address":                "",
+		"proxy":                  "",
 	})
 
 	// Verify we can set a value without panicking (smoke test for label compatibility)
@@ -125,8 +131,8 @@ func TestHTTPMonitorFactory_CreateMonitor(t *testing.T) {
 		t.Error("Client was not set")
 	}
 
-	if httpMonitor.SiteStatusCodeMonitor != collector {
-		t.Error("SiteStatusCodeMonitor was not set correctly")
+	if httpMonitor.Metrics != collector {
+		t.Error("Metrics was not set correctly")
 	}
 }
 
@@ -319,22 +325,19 @@ func TestHTTPMonitor_Run(t *testing.T) {
 			logger := log.New(&logBuf, "", 0)
 
 			// Create collector and register it
-			collector := prometheus.NewGaugeVec(prometheus.GaugeOpts{
-				Name: "labtime_http_site_status_code",
-				Help: "The status code of the site.",
-			}, []string{"http_monitor_site_name", "http_site_url"})
+			collector := HTTPMonitorFactory{}.CreateCollector()
 
 			reg := prometheus.NewRegistry()
 			reg.MustRegister(collector)
 
 			// Create monitor
 			monitor := &HTTPMonitor{
-				Label:                 testLabel,
-				URL:                   server.URL,
-				Method:                http.MethodHead,
-				Logger:                logger,
-				Client:                &http.Client{}, // Use real client for integration test
-				SiteStatusCodeMonitor: collector,
+				Label:   testLabel,
+				URL:     server.URL,
+				Method:  http.MethodHead,
+				Logger:  logger,
+				Client:  &http.Client{}, // Use real client for integration test
+				Metrics: collector,
 			}
 
 			// Run the monitor
@@ -350,9 +353,11 @@ func TestHTTPMonitor_Run(t *testing.T) {
 
 			if tt.expectMetric {
 				// Check that the metric was set correctly
-				gauge := collector.With(prometheus.Labels{
+				gauge := collector.StatusCode.With(prometheus.Labels{
 					"http_monitor_site_name": testLabel,
 					"http_site_url":          server.URL,
+					"address":                "",
+					"proxy":                  "",
 				})
 				metricValue := testutil.ToFloat64(gauge)
 
@@ -381,10 +386,7 @@ func TestHTTPMonitor_Run_NetworkError(t *testing.T) {
 	logger := log.New(&logBuf, "", 0)
 
 	// Create collector
-	collector := prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "labtime_http_site_status_code",
-		Help: "The status code of the site.",
-	}, []string{"http_monitor_site_name", "http_site_url"})
+	collector := HTTPMonitorFactory{}.CreateCollector()
 
 	// Create mock HTTP client that simulates network error
 	mockClient := &mockHTTPClient{
@@ -395,11 +397,11 @@ func TestHTTPMonitor_Run_NetworkError(t *testing.T) {
 
 	// Create monitor with mock client
 	monitor := &HTTPMonitor{
-		Label:                 testLabel,
-		URL:                   "http://test.example.com",
-		Logger:                logger,
-		Client:                mockClient,
-		SiteStatusCodeMonitor: collector,
+		Label:   testLabel,
+		URL:     "http://test.example.com",
+		Logger:  logger,
+		Client:  mockClient,
+		Metrics: collector,
 	}
 
 	// Run the monitor
@@ -469,20 +471,17 @@ func TestHTTPMonitor_pushToPrometheus(t *testing.T) {
 	var logBuf bytes.Buffer
 	logger := log.New(&logBuf, "", 0)
 
-	collector := prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "labtime_http_site_status_code",
-		Help: "The status code of the site.",
-	}, []string{"http_monitor_site_name", "http_site_url"})
+	collector := HTTPMonitorFactory{}.CreateCollector()
 
 	reg := prometheus.NewRegistry()
 	reg.MustRegister(collector)
 
 	monitor := &HTTPMonitor{
-		Label:                 testLabel,
-		URL:                   testURL,
-		Logger:                logger,
-		Client:                nil,
-		SiteStatusCodeMonitor: collector,
+		Label:   testLabel,
+		URL:     testURL,
+		Logger:  logger,
+		Client:  nil,
+		Metrics: collector,
 	}
 
 	data := &HTTPHealthCheckerData{
@@ -492,9 +491,11 @@ func TestHTTPMonitor_pushToPrometheus(t *testing.T) {
 	monitor.pushToPrometheus(data)
 
 	// Check metric value
-	gauge := collector.With(prometheus.Labels{
+	gauge := collector.StatusCode.With(prometheus.Labels{
 		"http_monitor_site_name": testLabel,
 		"http_site_url":          testURL,
+		"address":                "",
+		"proxy":                  "",
 	})
 	metricValue := testutil.ToFloat64(gauge)
 
@@ -508,3 +509,326 @@ func TestHTTPMonitor_pushToPrometheus(t *testing.T) {
 		t.Errorf("Expected log '%s', got '%s'", expectedLogLine, logOutput)
 	}
 }
+
+func TestHTTPMonitor_pushToPrometheus_Timings(t *testing.T) {
+	const (
+		testLabel = "test-site"
+		testURL   = "https://example.com"
+	)
+
+	collector := HTTPMonitorFactory{}.CreateCollector()
+
+	monitor := &HTTPMonitor{
+		Label:   testLabel,
+		URL:     testURL,
+		Logger:  log.New(bytes.NewBuffer(nil), "", 0),
+		Metrics: collector,
+	}
+
+	monitor.pushToPrometheus(&HTTPHealthCheckerData{
+		StatusCode: 200,
+		Duration:   150 * time.Millisecond,
+		Timings: HTTPPhaseTimings{
+			DNSLookup:    10 * time.Millisecond,
+			TCPConnect:   20 * time.Millisecond,
+			TLSHandshake: 30 * time.Millisecond,
+			FirstByte:    40 * time.Millisecond,
+			Transfer:     50 * time.Millisecond,
+		},
+	})
+
+	if count := testutil.CollectAndCount(collector.ResponseTime); count != 1 {
+		t.Errorf("Expected 1 response time series, got %d", count)
+	}
+
+	expectedPhases := map[string]float64{
+		"dns":        0.01,
+		"connect":    0.02,
+		"tls":        0.03,
+		"first_byte": 0.04,
+		"transfer":   0.05,
+	}
+	for phase, expected := range expectedPhases {
+		value := testutil.ToFloat64(collector.PhaseDuration.With(prometheus.Labels{
+			"http_monitor_site_name": testLabel,
+			"http_site_url":          testURL,
+			"address":                "",
+			"proxy":                  "",
+			"phase":                  phase,
+		}))
+		if abs(value-expected) > 1e-9 {
+			t.Errorf("Phase %s: expected %f, got %f", phase, expected, value)
+		}
+	}
+}
+
+func TestHTTPTargetProvider_GetTargets_Assertions(t *testing.T) {
+	provider := HTTPTargetProvider{}
+
+	targets, err := provider.GetTargets(&yamlconfig.YamlConfig{
+		HTTPStatusCode: []yamlconfig.HTTPMonitorDTO{
+			{
+				URL:        "https://example.com",
+				Method:     "GET",
+				Assertions: []yamlconfig.HTTPAssertionDTO{{Name: "healthy", Contains: "ok"}},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("GetTargets() returned unexpected error: %v", err)
+	}
+
+	if targets[0].BodyMaxSize != defaultHTTPBodyMaxSize {
+		t.Errorf("BodyMaxSize: expected %d, got %d", defaultHTTPBodyMaxSize, targets[0].BodyMaxSize)
+	}
+	if len(targets[0].Assertions) != 1 || targets[0].Assertions[0].Name != "healthy" {
+		t.Errorf("Assertions: unexpected value %+v", targets[0].Assertions)
+	}
+
+	_, err = provider.GetTargets(&yamlconfig.YamlConfig{
+		HTTPStatusCode: []yamlconfig.HTTPMonitorDTO{
+			{
+				URL:        "https://example.com",
+				Assertions: []yamlconfig.HTTPAssertionDTO{{Name: "invalid", Regex: "("}},
+			},
+		},
+	})
+	if err == nil {
+		t.Error("Expected error for invalid assertion but got none")
+	}
+}
+
+func TestHTTPTargetProvider_GetTargets_AssertionsMethod(t *testing.T) {
+	provider := HTTPTargetProvider{}
+	assertions := []yamlconfig.HTTPAssertionDTO{{Name: "healthy", Contains: "ok"}}
+
+	targets, err := provider.GetTargets(&yamlconfig.YamlConfig{
+		HTTPStatusCode: []yamlconfig.HTTPMonitorDTO{
+			{URL: "https://example.com", Assertions: assertions},
+			{URL: "https://example.com"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("GetTargets() returned unexpected error: %v", err)
+	}
+	if targets[0].Method != http.MethodGet {
+		t.Errorf("Expected GET by default with assertions, got %s", targets[0].Method)
+	}
+	if targets[1].Method != http.MethodHead {
+		t.Errorf("Expected HEAD by default without assertions, got %s", targets[1].Method)
+	}
+
+	_, err = provider.GetTargets(&yamlconfig.YamlConfig{
+		HTTPStatusCode: []yamlconfig.HTTPMonitorDTO{
+			{URL: "https://example.com", Method: http.MethodHead, Assertions: assertions},
+		},
+	})
+	if err == nil {
+		t.Error("Expected error for assertions with HEAD but got none")
+	}
+}
+
+func TestHTTPMonitor_Run_Assertions(t *testing.T) {
+	const testLabel = "test-site"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"status":"up","database":"down"}`))
+	}))
+	defer server.Close()
+
+	collector := HTTPMonitorFactory{}.CreateCollector()
+
+	monitor := &HTTPMonitor{
+		Label:  testLabel,
+		URL:    server.URL,
+		Method: http.MethodGet,
+		Assertions: []HTTPAssertion{
+			{Name: "status", JSONPath: "status", Equals: "up"},
+			{Name: "database", JSONPath: "database", Equals: "up"},
+		},
+		Logger:  log.New(bytes.NewBuffer(nil), "", 0),
+		Client:  &http.Client{},
+		Metrics: collector,
+	}
+
+	if err := monitor.Run(t.Context()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := map[string]float64{"status": 1, "database": 0}
+	for assertion, value := range expected {
+		got := testutil.ToFloat64(collector.AssertionSuccess.With(prometheus.Labels{
+			"http_monitor_site_name": testLabel,
+			"http_site_url":          server.URL,
+			"address":                "",
+			"proxy":                  "",
+			"assertion":              assertion,
+		}))
+		if got != value {
+			t.Errorf("Assertion %s: expected %f, got %f", assertion, value, got)
+		}
+	}
+}
+
+func TestHTTPMonitor_httpHealthCheck_BodyMaxSize(t *testing.T) {
+	mockClient := &mockHTTPClient{
+		doFunc: func(_ *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(strings.NewReader("0123456789marker")),
+				Header:     make(http.Header),
+			}, nil
+		},
+	}
+
+	monitor := &HTTPMonitor{
+		Label:       "test",
+		URL:         "http://test.example.com",
+		BodyMaxSize: 10,
+		Assertions:  []HTTPAssertion{{Name: "marker", Contains: "marker"}},
+		Logger:      log.New(bytes.NewBuffer(nil), "", 0),
+		Client:      mockClient,
+	}
+
+	data, err := monitor.httpHealthCheck(t.Context())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The marker is after the size limit and must not be seen
+	if len(data.Assertions) != 1 || data.Assertions[0].Err == nil {
+		t.Errorf("Expected the assertion to fail on the truncated body, got %+v", data.Assertions)
+	}
+}
+
+func TestHTTPMonitor_Run_Up(t *testing.T) {
+	tests := []struct {
+		name           string
+		statusCode     int
+		expectedStatus HTTPStatusMatcher
+		body           string
+		assertions     []HTTPAssertion
+		expectedUp     float64
+		expectedReason string
+	}{
+		{
+			name:           "default expected status",
+			statusCode:     200,
+			expectedUp:     1,
+			expectedReason: "",
+		},
+		{
+			name:           "unexpected status",
+			statusCode:     503,
+			expectedUp:     0,
+			expectedReason: httpDownReasonUnexpectedStatus,
+		},
+		{
+			name:           "custom expected status",
+			statusCode:     401,
+			expectedStatus: HTTPStatusMatcher{{Min: 200, Max: 299}, {Min: 401, Max: 401}},
+			expectedUp:     1,
+			expectedReason: "",
+		},
+		{
+			name:           "failing assertion",
+			statusCode:     200,
+			body:           "maintenance",
+			assertions:     []HTTPAssertion{{Name: "healthy", Contains: "ok"}},
+			expectedUp:     0,
+			expectedReason: httpDownReasonAssertionFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			const testLabel = "test-site"
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tt.statusCode)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			collector := HTTPMonitorFactory{}.CreateCollector()
+
+			monitor := &HTTPMonitor{
+				Label:          testLabel,
+				URL:            server.URL,
+				Method:         http.MethodGet,
+				ExpectedStatus: tt.expectedStatus,
+				Assertions:     tt.assertions,
+				Logger:         log.New(bytes.NewBuffer(nil), "", 0),
+				Client:         &http.Client{},
+				Metrics:        collector,
+			}
+
+			if err := monitor.Run(t.Context()); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if count := testutil.CollectAndCount(collector.Up); count != 1 {
+				t.Errorf("Expected a single up series, got %d", count)
+			}
+
+			got := testutil.ToFloat64(collector.Up.With(prometheus.Labels{
+				"http_monitor_site_name": testLabel,
+				"http_site_url":          server.URL,
+				"address":                "",
+				"proxy":                  "",
+				"reason":                 tt.expectedReason,
+			}))
+			if got != tt.expectedUp {
+				t.Errorf("Expected up %f, got %f", tt.expectedUp, got)
+			}
+		})
+	}
+}
+
+func TestHTTPMonitor_Run_NetworkError_Up(t *testing.T) {
+	const (
+		testLabel = "test-site"
+		testURL   = "http://test.example.com"
+	)
+
+	collector := HTTPMonitorFactory{}.CreateCollector()
+	labels := prometheus.Labels{
+		"http_monitor_site_name": testLabel,
+		"http_site_url":          testURL,
+		"address":                "",
+		"proxy":                  "",
+	}
+
+	// Simulate a previous successful check
+	collector.StatusCode.With(labels).Set(200)
+	collector.Up.MustCurryWith(labels).WithLabelValues("").Set(1)
+
+	monitor := &HTTPMonitor{
+		Label:  testLabel,
+		URL:    testURL,
+		Logger: log.New(bytes.NewBuffer(nil), "", 0),
+		Client: &mockHTTPClient{
+			doFunc: func(_ *http.Request) (*http.Response, error) {
+				return nil, &net.OpError{Op: "dial", Err: &os.SyscallError{Syscall: "connect", Err: syscall.ECONNREFUSED}}
+			},
+		},
+		Metrics: collector,
+	}
+
+	if err := monitor.Run(t.Context()); err == nil {
+		t.Fatal("Expected error but got none")
+	}
+
+	if got := testutil.ToFloat64(collector.StatusCode.With(labels)); got != 0 {
+		t.Errorf("Expected stale status code to be reset to 0, got %f", got)
+	}
+
+	if count := testutil.CollectAndCount(collector.Up); count != 1 {
+		t.Errorf("Expected a single up series, got %d", count)
+	}
+
+	got := testutil.ToFloat64(collector.Up.MustCurryWith(labels).WithLabelValues(httpDownReasonConnectionRefused))
+	if got != 0 {
+		t.Errorf("Expected up 0, got %f", got)
+	}
+}
@@ -0,0 +1,85 @@
+package monitors
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/http/httpproxy"
+)
+
+// HTTPProxy is the outbound proxy of an HTTP target.
+type HTTPProxy struct {
+	URL     *url.URL
+	NoProxy []string
+
+	proxyFunc func(*url.URL) (*url.URL, error)
+}
+
+// newHTTPProxy validates the proxy settings of a target. It returns nil when
+// no proxy is configured, the transport then honours the proxy environment
+// variables.
+func newHTTPProxy(proxyURL string, noProxy []string) (*HTTPProxy, error) {
+	if proxyURL == "" {
+		if len(noProxy) > 0 {
+			return nil, errors.New("no_proxy requires proxy_url")
+		}
+		return nil, nil //nolint:nilnil // no proxy is not an error
+	}
+
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid proxy_url %q", proxyURL)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, errors.Errorf("unsupported proxy_url scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, errors.Errorf("missing host in proxy_url %q", withoutUserinfo(u))
+	}
+
+	config := &httpproxy.Config{
+		HTTPProxy:  proxyURL,
+		HTTPSProxy: proxyURL,
+		NoProxy:    strings.Join(noProxy, ","),
+	}
+
+	return &HTTPProxy{
+		URL:       u,
+		NoProxy:   noProxy,
+		proxyFunc: config.ProxyFunc(),
+	}, nil
+}
+
+// Proxy implements the http.Transport Proxy function. Requests to hosts
+// matching NoProxy, localhost and loopback addresses are sent directly.
+func (p *HTTPProxy) Proxy(req *http.Request) (*url.URL, error) {
+	return p.proxyFunc(req.URL)
+}
+
+// Label returns the proxy used to reach the URL, without credentials, or an
+// empty string when the URL is reached directly.
+func (p *HTTPProxy) Label(rawURL string) string {
+	if p == nil {
+		return ""
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	proxy, err := p.proxyFunc(u)
+	if err != nil || proxy == nil {
+		return ""
+	}
+	return withoutUserinfo(proxy)
+}
+
+// withoutUserinfo returns the URL without the username and password.
+func withoutUserinfo(u *url.URL) string {
+	copied := *u
+	copied.User = nil
+	return copied.String()
+}
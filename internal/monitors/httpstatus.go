@@ -0,0 +1,142 @@
+package monitors
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// Reasons reported by the labtime_http_up metric when a target is down.
+const (
+	httpDownReasonUnexpectedStatus  = "unexpected_status"
+	httpDownReasonAssertionFailed   = "assertion_failed"
+	httpDownReasonTimeout           = "timeout"
+	httpDownReasonDNS               = "dns"
+	httpDownReasonConnectionRefused = "connection_refused"
+	httpDownReasonTLS               = "tls"
+	httpDownReasonAuthentication    = "authentication"
+	httpDownReasonTooManyRedirects  = "too_many_redirects"
+	httpDownReasonRequestError      = "request_error"
+)
+
+// defaultExpectedStatus is used when no expected status is configured.
+var defaultExpectedStatus = HTTPStatusMatcher{{Min: 200, Max: 299}}
+
+// HTTPStatusRange is an inclusive range of HTTP status codes.
+type HTTPStatusRange struct {
+	Min int
+	Max int
+}
+
+// HTTPStatusMatcher matches HTTP status codes against a list of ranges.
+type HTTPStatusMatcher []HTTPStatusRange
+
+// Match reports whether the status code is expected. An empty matcher
+// accepts 2xx status codes.
+func (m HTTPStatusMatcher) Match(statusCode int) bool {
+	if len(m) == 0 {
+		m = defaultExpectedStatus
+	}
+	for _, r := range m {
+		if statusCode >= r.Min && statusCode <= r.Max {
+			return true
+		}
+	}
+	return false
+}
+
+// parseHTTPStatusMatcher parses expected status specifications. Each
+// specification is a comma separated list of status codes (200), classes
+// (2xx) or ranges (200-299).
+func parseHTTPStatusMatcher(specs []string) (HTTPStatusMatcher, error) {
+	matcher := HTTPStatusMatcher{}
+	for _, spec := range specs {
+		for part := range strings.SplitSeq(spec, ",") {
+			r, err := parseHTTPStatusRange(strings.TrimSpace(part))
+			if err != nil {
+				return nil, err
+			}
+			matcher = append(matcher, r)
+		}
+	}
+	if len(matcher) == 0 {
+		return defaultExpectedStatus, nil
+	}
+	return matcher, nil
+}
+
+func parseHTTPStatusRange(s string) (HTTPStatusRange, error) {
+	lower := strings.ToLower(s)
+	if len(lower) == 3 && strings.HasSuffix(lower, "xx") {
+		class, err := strconv.Atoi(lower[:1])
+		if err != nil || class < 1 || class > 5 {
+			return HTTPStatusRange{}, errors.Errorf("invalid status class %q", s)
+		}
+		return HTTPStatusRange{Min: class * 100, Max: class*100 + 99}, nil
+	}
+
+	minStr, maxStr, isRange := strings.Cut(s, "-")
+	if !isRange {
+		maxStr = minStr
+	}
+	minCode, err := parseHTTPStatusCode(strings.TrimSpace(minStr))
+	if err != nil {
+		return HTTPStatusRange{}, err
+	}
+	maxCode, err := parseHTTPStatusCode(strings.TrimSpace(maxStr))
+	if err != nil {
+		return HTTPStatusRange{}, err
+	}
+	if minCode > maxCode {
+		return HTTPStatusRange{}, errors.Errorf("invalid status range %q", s)
+	}
+	return HTTPStatusRange{Min: minCode, Max: maxCode}, nil
+}
+
+func parseHTTPStatusCode(s string) (int, error) {
+	code, err := strconv.Atoi(s)
+	if err != nil || code < 100 || code > 599 {
+		return 0, errors.Errorf("invalid status code %q", s)
+	}
+	return code, nil
+}
+
+// httpErrorReason classifies a request error into a labtime_http_up reason.
+func httpErrorReason(err error) string {
+	var (
+		dnsErr       *net.DNSError
+		netErr       net.Error
+		certErr      *tls.CertificateVerificationError
+		recordErr    tls.RecordHeaderError
+		authorityErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+		invalidErr   x509.CertificateInvalidError
+	)
+
+	switch {
+	case errors.Is(err, ErrHTTPAuthentication):
+		return httpDownReasonAuthentication
+	case errors.Is(err, ErrTooManyRedirects):
+		return httpDownReasonTooManyRedirects
+	case errors.As(err, &dnsErr):
+		return httpDownReasonDNS
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return httpDownReasonTimeout
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return httpDownReasonConnectionRefused
+	case errors.As(err, &certErr),
+		errors.As(err, &recordErr),
+		errors.As(err, &authorityErr),
+		errors.As(err, &hostnameErr),
+		errors.As(err, &invalidErr):
+		return httpDownReasonTLS
+	default:
+		return httpDownReasonRequestError
+	}
+}
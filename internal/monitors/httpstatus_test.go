@@ -0,0 +1,117 @@
+package monitors
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestParseHTTPStatusMatcher(t *testing.T) {
+	tests := []struct {
+		name        string
+		specs       []string
+		matching    []int
+		notMatching []int
+		expectError bool
+	}{
+		{
+			name:        "default",
+			specs:       nil,
+			matching:    []int{200, 204, 299},
+			notMatching: []int{199, 301, 404, 500},
+		},
+		{
+			name:        "single status codes",
+			specs:       []string{"200", "401"},
+			matching:    []int{200, 401},
+			notMatching: []int{201, 404},
+		},
+		{
+			name:        "class",
+			specs:       []string{"3xx"},
+			matching:    []int{300, 301, 399},
+			notMatching: []int{200, 400},
+		},
+		{
+			name:        "comma separated range and code",
+			specs:       []string{"200-299, 401"},
+			matching:    []int{200, 250, 299, 401},
+			notMatching: []int{300, 403},
+		},
+		{
+			name:        "uppercase class",
+			specs:       []string{"4XX"},
+			matching:    []int{404},
+			notMatching: []int{200},
+		},
+		{name: "invalid code", specs: []string{"abc"}, expectError: true},
+		{name: "out of bounds code", specs: []string{"600"}, expectError: true},
+		{name: "invalid class", specs: []string{"9xx"}, expectError: true},
+		{name: "reversed range", specs: []string{"299-200"}, expectError: true},
+		{name: "empty part", specs: []string{"200,"}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matcher, err := parseHTTPStatusMatcher(tt.specs)
+			if tt.expectError {
+				if err == nil {
+					t.Fatal("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			for _, code := range tt.matching {
+				if !matcher.Match(code) {
+					t.Errorf("Expected %d to match %v", code, tt.specs)
+				}
+			}
+			for _, code := range tt.notMatching {
+				if matcher.Match(code) {
+					t.Errorf("Expected %d not to match %v", code, tt.specs)
+				}
+			}
+		})
+	}
+}
+
+func TestHTTPStatusMatcher_Match_Empty(t *testing.T) {
+	var matcher HTTPStatusMatcher
+
+	if !matcher.Match(200) {
+		t.Error("Expected empty matcher to accept 200")
+	}
+	if matcher.Match(500) {
+		t.Error("Expected empty matcher to reject 500")
+	}
+}
+
+func TestHTTPErrorReason(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{"dns", &net.DNSError{Err: "no such host", Name: "example.invalid", IsNotFound: true}, httpDownReasonDNS},
+		{"deadline", fmt.Errorf("request: %w", context.DeadlineExceeded), httpDownReasonTimeout},
+		{"net timeout", &net.OpError{Op: "dial", Err: os.ErrDeadlineExceeded}, httpDownReasonTimeout},
+		{"connection refused", &net.OpError{Op: "dial", Err: &os.SyscallError{Syscall: "connect", Err: syscall.ECONNREFUSED}}, httpDownReasonConnectionRefused},
+		{"unknown authority", fmt.Errorf("tls: %w", x509.UnknownAuthorityError{}), httpDownReasonTLS},
+		{"other", fmt.Errorf("something went wrong"), httpDownReasonRequestError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := httpErrorReason(tt.err); got != tt.expected {
+				t.Errorf("httpErrorReason() = %s, want %s", got, tt.expected)
+			}
+		})
+	}
+}
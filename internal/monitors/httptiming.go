@@ -0,0 +1,139 @@
+package monitors
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// HTTPPhaseTimings holds the duration of each phase of an HTTP request.
+type HTTPPhaseTimings struct {
+	DNSLookup    time.Duration
+	TCPConnect   time.Duration
+	TLSHandshake time.Duration
+	// FirstByte is the time between the request being written and the first
+	// response byte being received (i.e. the server processing time).
+	FirstByte time.Duration
+	Transfer  time.Duration
+}
+
+// httpTimer records the phase timestamps of an HTTP request using
+// net/http/httptrace hooks.
+type httpTimer struct {
+	mu sync.Mutex
+
+	start        time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+	transferDone time.Time
+}
+
+func newHTTPTimer() *httpTimer {
+	return &httpTimer{start: time.Now()}
+}
+
+// clientTrace returns the httptrace hooks feeding the timer.
+func (t *httpTimer) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GetConn: func(_ string) {
+			t.startHop()
+		},
+		DNSStart: func(_ httptrace.DNSStartInfo) {
+			t.record(&t.dnsStart)
+		},
+		DNSDone: func(_ httptrace.DNSDoneInfo) {
+			t.record(&t.dnsDone)
+		},
+		ConnectStart: func(_, _ string) {
+			t.recordFirst(&t.connectStart)
+		},
+		ConnectDone: func(_, _ string, _ error) {
+			t.record(&t.connectDone)
+		},
+		TLSHandshakeStart: func() {
+			t.record(&t.tlsStart)
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, _ error) {
+			t.record(&t.tlsDone)
+		},
+		WroteRequest: func(_ httptrace.WroteRequestInfo) {
+			t.record(&t.wroteRequest)
+		},
+		GotFirstResponseByte: func() {
+			t.record(&t.firstByte)
+		},
+	}
+}
+
+// startHop resets the phase timestamps when a request of the redirect chain
+// starts, so the phases are those of the request answering with the final
+// response.
+func (t *httpTimer) startHop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.dnsStart, t.dnsDone = time.Time{}, time.Time{}
+	t.connectStart, t.connectDone = time.Time{}, time.Time{}
+	t.tlsStart, t.tlsDone = time.Time{}, time.Time{}
+	t.wroteRequest, t.firstByte = time.Time{}, time.Time{}
+}
+
+// done marks the end of the response body transfer.
+func (t *httpTimer) done() {
+	t.record(&t.transferDone)
+}
+
+// record sets the given timestamp to the current time. Retries trigger the
+// hooks several times, the last occurrence wins.
+func (t *httpTimer) record(ts *time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	*ts = time.Now()
+}
+
+// recordFirst sets the given timestamp to the current time only if it was not
+// set yet for the current request. It is used for hooks that can fire
+// concurrently for a single request (e.g. dual-stack dialing).
+func (t *httpTimer) recordFirst(ts *time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if ts.IsZero() {
+		*ts = time.Now()
+	}
+}
+
+// total returns the duration of the whole request, from its creation to the
+// end of the body transfer.
+func (t *httpTimer) total() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return between(t.start, t.transferDone)
+}
+
+// timings computes the phase durations from the recorded timestamps. Phases
+// that did not happen (e.g. DNS lookup for an IP address, TLS handshake for
+// plain HTTP or when a connection is reused) are reported as zero.
+func (t *httpTimer) timings() HTTPPhaseTimings {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return HTTPPhaseTimings{
+		DNSLookup:    between(t.dnsStart, t.dnsDone),
+		TCPConnect:   between(t.connectStart, t.connectDone),
+		TLSHandshake: between(t.tlsStart, t.tlsDone),
+		FirstByte:    between(t.wroteRequest, t.firstByte),
+		Transfer:     between(t.firstByte, t.transferDone),
+	}
+}
+
+func between(start, end time.Time) time.Duration {
+	if start.IsZero() || end.IsZero() || end.Before(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
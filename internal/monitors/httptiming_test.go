@@ -0,0 +1,121 @@
+package monitors
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"testing"
+	"time"
+)
+
+func TestHTTPTimer_timings(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	timer := newHTTPTimer()
+	ctx := httptrace.WithClientTrace(t.Context(), timer.clientTrace())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, http.NoBody)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		t.Fatalf("Failed to read body: %v", err)
+	}
+	resp.Body.Close()
+	timer.done()
+
+	timings := timer.timings()
+
+	// The test server listens on an IP address, no DNS lookup is performed
+	if timings.DNSLookup != 0 {
+		t.Errorf("Expected no DNS lookup time, got %v", timings.DNSLookup)
+	}
+	if timings.TCPConnect <= 0 {
+		t.Errorf("Expected TCP connect time to be recorded, got %v", timings.TCPConnect)
+	}
+	if timings.TLSHandshake <= 0 {
+		t.Errorf("Expected TLS handshake time to be recorded, got %v", timings.TLSHandshake)
+	}
+	if total := timer.total(); total < timings.TCPConnect+timings.TLSHandshake {
+		t.Errorf("Expected total duration %v to include connect and TLS phases", total)
+	}
+}
+
+func TestHTTPTimer_timings_Redirect(t *testing.T) {
+	const delay = 300 * time.Millisecond
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			time.Sleep(delay)
+			// Close the connection so the redirect opens a new one.
+			w.Header().Set("Connection", "close")
+			http.Redirect(w, r, "/next", http.StatusFound)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	timer := newHTTPTimer()
+	ctx := httptrace.WithClientTrace(t.Context(), timer.clientTrace())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, http.NoBody)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		t.Fatalf("Failed to read body: %v", err)
+	}
+	resp.Body.Close()
+	timer.done()
+
+	// The phases are those of the redirected request, the total includes the
+	// whole chain.
+	timings := timer.timings()
+	if timings.TCPConnect <= 0 || timings.TCPConnect >= delay {
+		t.Errorf("Expected the TCP connect time of the redirected request, got %v", timings.TCPConnect)
+	}
+	if timings.FirstByte >= delay {
+		t.Errorf("Expected the first byte time of the redirected request, got %v", timings.FirstByte)
+	}
+	if total := timer.total(); total < delay {
+		t.Errorf("Expected total duration %v to include the redirect", total)
+	}
+}
+
+func TestBetween(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		start    time.Time
+		end      time.Time
+		expected time.Duration
+	}{
+		{"both set", now, now.Add(time.Second), time.Second},
+		{"start not set", time.Time{}, now, 0},
+		{"end not set", now, time.Time{}, 0},
+		{"end before start", now, now.Add(-time.Second), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := between(tt.start, tt.end); got != tt.expected {
+				t.Errorf("between() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,252 @@
+package monitors
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"aireone.xyz/labtime/internal/yamlconfig"
+	"github.com/pkg/errors"
+)
+
+var ErrTooManyRedirects = errors.New("too many redirects")
+
+// IP protocols supported by the ip_protocol setting of the HTTP targets.
+const (
+	ipProtocolIPv4 = "ipv4"
+	ipProtocolIPv6 = "ipv6"
+	ipProtocolBoth = "both"
+)
+
+func isValidIPProtocol(p string) bool {
+	return p == ipProtocolIPv4 || p == ipProtocolIPv6 || p == ipProtocolBoth
+}
+
+// dialNetwork returns the network used to dial the target for the IP
+// protocol.
+func dialNetwork(ipProtocol string) string {
+	switch ipProtocol {
+	case ipProtocolIPv4:
+		return "tcp4"
+	case ipProtocolIPv6:
+		return "tcp6"
+	default:
+		return "tcp"
+	}
+}
+
+// lookupNetwork returns the network used to resolve the target host for the
+// IP protocol.
+func lookupNetwork(ipProtocol string) string {
+	switch ipProtocol {
+	case ipProtocolIPv4:
+		return "ip4"
+	case ipProtocolIPv6:
+		return "ip6"
+	default:
+		return "ip"
+	}
+}
+
+// unixSocketScheme is the scheme of the HTTP targets served over a Unix
+// domain socket, e.g. unix:///run/app.sock:/health.
+const unixSocketScheme = "unix://"
+
+// unixSocketHost is the host of the requests sent over a Unix domain socket.
+const unixSocketHost = "localhost"
+
+// parseUnixSocketURL splits a unix:// target URL into the socket path and the
+// URL of the request sent over the socket. The request path defaults to /.
+func parseUnixSocketURL(rawURL string) (string, string, error) {
+	rest, ok := strings.CutPrefix(rawURL, unixSocketScheme)
+	if !ok {
+		return "", "", errors.Errorf("URL %q is not a unix socket URL", rawURL)
+	}
+
+	socketPath, path, _ := strings.Cut(rest, ":")
+	if socketPath == "" {
+		return "", "", errors.Errorf("missing socket path in URL %q", rawURL)
+	}
+	if path == "" {
+		path = "/"
+	}
+	if !strings.HasPrefix(path, "/") {
+		return "", "", errors.Errorf("invalid request path %q in URL %q", path, rawURL)
+	}
+
+	return socketPath, "http://" + unixSocketHost + path, nil
+}
+
+type dialAddressKey struct{}
+
+// dialAddress pins the connections to Host to the IP address.
+type dialAddress struct {
+	Host string
+	IP   string
+}
+
+// withDialAddress returns a context in which the connections to host are
+// made to the given IP address instead of the resolved ones.
+func withDialAddress(ctx context.Context, host, ip string) context.Context {
+	return context.WithValue(ctx, dialAddressKey{}, dialAddress{Host: host, IP: ip})
+}
+
+func dialAddressFrom(ctx context.Context) (dialAddress, bool) {
+	address, ok := ctx.Value(dialAddressKey{}).(dialAddress)
+	return address, ok
+}
+
+// newHTTPDialContext creates the transport dial function of an HTTP target.
+// It restricts the connections to the IP protocol and honors the address
+// pinned in the request context, if any.
+func newHTTPDialContext(ipProtocol string) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if network == "tcp" {
+			network = dialNetwork(ipProtocol)
+		}
+
+		if pinned, ok := dialAddressFrom(ctx); ok {
+			host, port, err := net.SplitHostPort(addr)
+			if err == nil && host == pinned.Host {
+				addr = net.JoinHostPort(pinned.IP, port)
+			}
+		}
+
+		return dialer.DialContext(ctx, network, addr)
+	}
+}
+
+// newHTTPTransport creates the dedicated transport of an HTTP target.
+func newHTTPTransport(target HTTPTarget) *http.Transport {
+	transport, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		transport = &http.Transport{}
+	}
+	transport = transport.Clone()
+
+	if target.TLSConfig != nil {
+		transport.TLSClientConfig = target.TLSConfig.Clone()
+	}
+	transport.DialContext = newHTTPDialContext(target.IPProtocol)
+	if target.Proxy != nil {
+		transport.Proxy = target.Proxy.Proxy
+	}
+	if target.SocketPath != "" {
+		transport.DialContext = newUnixSocketDialContext(target.SocketPath)
+	}
+	// Idle connections are bound to the address they were dialed to, they
+	// can't be reused when each address is probed individually.
+	transport.DisableKeepAlives = target.DisableKeepAlives || target.ProbeAllAddresses
+
+	return transport
+}
+
+// newUnixSocketDialContext creates a transport dial function connecting to
+// the Unix domain socket, whatever the requested address.
+func newUnixSocketDialContext(socketPath string) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{}
+	return func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return dialer.DialContext(ctx, "unix", socketPath)
+	}
+}
+
+// newHTTPRedirectPolicy creates the http.Client CheckRedirect function of an
+// HTTP target.
+func newHTTPRedirectPolicy(followRedirects bool, maxRedirects int) func(*http.Request, []*http.Request) error {
+	return func(_ *http.Request, via []*http.Request) error {
+		if !followRedirects {
+			return http.ErrUseLastResponse
+		}
+		if len(via) > maxRedirects {
+			return errors.Wrapf(ErrTooManyRedirects, "stopped after %d redirects", maxRedirects)
+		}
+		return nil
+	}
+}
+
+// redirectsOf returns the final URL of the response and the number of
+// redirects followed to get it.
+func redirectsOf(resp *http.Response) (string, int) {
+	if resp.Request == nil {
+		return "", 0
+	}
+
+	redirects := 0
+	for r := resp.Request; r.Response != nil && r.Response.Request != nil; r = r.Response.Request {
+		redirects++
+	}
+	return resp.Request.URL.String(), redirects
+}
+
+// newHTTPTLSConfig creates the TLS client configuration of an HTTP target. It
+// returns nil when no TLS settings are configured.
+func newHTTPTLSConfig(dto *yamlconfig.HTTPTLSDTO) (*tls.Config, error) {
+	if dto == nil {
+		return nil, nil //nolint:nilnil // no TLS settings is not an error
+	}
+
+	config := &tls.Config{
+		ServerName:         dto.ServerName,
+		InsecureSkipVerify: dto.InsecureSkipVerify, //nolint:gosec // explicitly requested by the configuration
+		MinVersion:         tls.VersionTLS12,
+	}
+
+	if dto.MinVersion != "" {
+		version, err := parseTLSVersion(dto.MinVersion)
+		if err != nil {
+			return nil, err
+		}
+		config.MinVersion = version
+	}
+
+	if dto.CAFile != "" {
+		pem, err := os.ReadFile(dto.CAFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error reading ca_file %q", dto.CAFile)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf("no certificate found in ca_file %q", dto.CAFile)
+		}
+		config.RootCAs = pool
+	}
+
+	if dto.CertFile != "" || dto.KeyFile != "" {
+		if dto.CertFile == "" || dto.KeyFile == "" {
+			return nil, errors.New("cert_file and key_file must be set together")
+		}
+		cert, err := tls.LoadX509KeyPair(dto.CertFile, dto.KeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "error loading client certificate")
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+// parseTLSVersion parses a TLS version in the "1.2" format.
+func parseTLSVersion(version string) (uint16, error) {
+	switch version {
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, errors.Errorf("invalid TLS version %q", version)
+	}
+}
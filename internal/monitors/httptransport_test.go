@@ -0,0 +1,537 @@
+package monitors
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"aireone.xyz/labtime/internal/yamlconfig"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestNewHTTPTLSConfig(t *testing.T) {
+	ca := newTestCertificate(t, &x509.Certificate{IsCA: true}, nil)
+	client := newTestCertificate(t, &x509.Certificate{}, ca)
+
+	caFile := writeTestFile(t, "ca.pem", ca.PEM())
+	certFile := writeTestFile(t, "client.pem", client.PEM())
+	keyFile := writeTestFile(t, "client-key.pem", client.KeyPEM(t))
+	invalidFile := writeTestFile(t, "invalid.pem", []byte("not a certificate"))
+
+	tests := []struct {
+		name        string
+		dto         *yamlconfig.HTTPTLSDTO
+		check       func(t *testing.T, config *tls.Config)
+		expectError bool
+	}{
+		{
+			name: "no settings",
+			dto:  nil,
+			check: func(t *testing.T, config *tls.Config) {
+				t.Helper()
+				if config != nil {
+					t.Errorf("Expected nil config, got %+v", config)
+				}
+			},
+		},
+		{
+			name: "all settings",
+			dto: &yamlconfig.HTTPTLSDTO{
+				CAFile:             caFile,
+				CertFile:           certFile,
+				KeyFile:            keyFile,
+				ServerName:         "internal.example.com",
+				InsecureSkipVerify: true,
+				MinVersion:         "1.3",
+			},
+			check: func(t *testing.T, config *tls.Config) {
+				t.Helper()
+				if config.RootCAs == nil {
+					t.Error("Expected RootCAs to be set")
+				}
+				if len(config.Certificates) != 1 {
+					t.Errorf("Expected 1 client certificate, got %d", len(config.Certificates))
+				}
+				if config.ServerName != "internal.example.com" {
+					t.Errorf("Expected ServerName 'internal.example.com', got %q", config.ServerName)
+				}
+				if !config.InsecureSkipVerify {
+					t.Error("Expected InsecureSkipVerify to be set")
+				}
+				if config.MinVersion != tls.VersionTLS13 {
+					t.Errorf("Expected MinVersion TLS 1.3, got %x", config.MinVersion)
+				}
+			},
+		},
+		{
+			name: "default min version",
+			dto:  &yamlconfig.HTTPTLSDTO{ServerName: "example.com"},
+			check: func(t *testing.T, config *tls.Config) {
+				t.Helper()
+				if config.MinVersion != tls.VersionTLS12 {
+					t.Errorf("Expected MinVersion TLS 1.2, got %x", config.MinVersion)
+				}
+			},
+		},
+		{name: "invalid min version", dto: &yamlconfig.HTTPTLSDTO{MinVersion: "2.0"}, expectError: true},
+		{name: "missing ca file", dto: &yamlconfig.HTTPTLSDTO{CAFile: caFile + ".missing"}, expectError: true},
+		{name: "invalid ca file", dto: &yamlconfig.HTTPTLSDTO{CAFile: invalidFile}, expectError: true},
+		{name: "cert without key", dto: &yamlconfig.HTTPTLSDTO{CertFile: certFile}, expectError: true},
+		{name: "invalid key pair", dto: &yamlconfig.HTTPTLSDTO{CertFile: certFile, KeyFile: invalidFile}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := newHTTPTLSConfig(tt.dto)
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			tt.check(t, config)
+		})
+	}
+}
+
+func TestNewHTTPTransport(t *testing.T) {
+	config := &tls.Config{ServerName: "example.com", MinVersion: tls.VersionTLS12}
+
+	first := newHTTPTransport(HTTPTarget{TLSConfig: config})
+	second := newHTTPTransport(HTTPTarget{})
+
+	if first == http.DefaultTransport || second == http.DefaultTransport {
+		t.Error("Expected a dedicated transport per target")
+	}
+	if first.TLSClientConfig == nil || first.TLSClientConfig.ServerName != "example.com" {
+		t.Errorf("Expected the TLS config to be applied, got %+v", first.TLSClientConfig)
+	}
+	if second.TLSClientConfig != nil && second.TLSClientConfig.ServerName != "" {
+		t.Errorf("Expected the TLS config not to leak between targets, got %+v", second.TLSClientConfig)
+	}
+}
+
+func TestHTTPMonitor_Run_MutualTLS(t *testing.T) {
+	ca := newTestCertificate(t, &x509.Certificate{IsCA: true}, nil)
+	serverCert := newTestCertificate(t, &x509.Certificate{DNSNames: []string{"internal.example.com"}}, ca)
+	clientCert := newTestCertificate(t, &x509.Certificate{}, ca)
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.Cert)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {}))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverCert.TLSCertificate()},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+		MinVersion:   tls.VersionTLS12,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	tlsConfig, err := newHTTPTLSConfig(&yamlconfig.HTTPTLSDTO{
+		CAFile:     writeTestFile(t, "ca.pem", ca.PEM()),
+		CertFile:   writeTestFile(t, "client.pem", clientCert.PEM()),
+		KeyFile:    writeTestFile(t, "client-key.pem", clientCert.KeyPEM(t)),
+		ServerName: "internal.example.com",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	collector := HTTPMonitorFactory{}.CreateCollector()
+	target := HTTPTarget{
+		Name:      "internal",
+		URL:       server.URL,
+		Method:    http.MethodGet,
+		Interval:  60,
+		TLSConfig: tlsConfig,
+	}
+	monitor := HTTPMonitorFactory{}.CreateMonitor(target, collector, log.New(bytes.NewBuffer(nil), "", 0))
+
+	if err := monitor.Run(t.Context()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	got := testutil.ToFloat64(collector.StatusCode.With(prometheus.Labels{
+		"http_monitor_site_name": "internal",
+		"http_site_url":          server.URL,
+		"address":                "",
+		"proxy":                  "",
+	}))
+	if got != http.StatusOK {
+		t.Errorf("Expected status code 200, got %f", got)
+	}
+}
+
+func TestHTTPMonitor_Run_Redirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/login", http.StatusFound)
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	mux.HandleFunc("/login", func(_ http.ResponseWriter, _ *http.Request) {})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		name              string
+		path              string
+		followRedirects   bool
+		expectedStatus    int
+		expectedRedirects int
+		expectedFinalURL  string
+		expectedReason    string
+	}{
+		{
+			name:              "follow redirects",
+			path:              "/health",
+			followRedirects:   true,
+			expectedStatus:    http.StatusOK,
+			expectedRedirects: 1,
+			expectedFinalURL:  server.URL + "/login",
+		},
+		{
+			name:              "do not follow redirects",
+			path:              "/health",
+			followRedirects:   false,
+			expectedStatus:    http.StatusFound,
+			expectedRedirects: 0,
+			expectedFinalURL:  server.URL + "/health",
+			expectedReason:    httpDownReasonUnexpectedStatus,
+		},
+		{
+			name:            "too many redirects",
+			path:            "/loop",
+			followRedirects: true,
+			expectedReason:  httpDownReasonTooManyRedirects,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := HTTPMonitorFactory{}.CreateCollector()
+			target := HTTPTarget{
+				Name:            "site",
+				URL:             server.URL + tt.path,
+				Method:          http.MethodGet,
+				Interval:        60,
+				Timeout:         5,
+				FollowRedirects: tt.followRedirects,
+				MaxRedirects:    3,
+			}
+			monitor := HTTPMonitorFactory{}.CreateMonitor(target, collector, log.New(bytes.NewBuffer(nil), "", 0))
+
+			err := monitor.Run(t.Context())
+
+			labels := prometheus.Labels{
+				"http_monitor_site_name": "site",
+				"http_site_url":          target.URL,
+				"address":                "",
+				"proxy":                  "",
+			}
+			up := collector.Up.MustCurryWith(labels).WithLabelValues(tt.expectedReason)
+			expectedUp := 0.0
+			if tt.expectedReason == "" {
+				expectedUp = 1
+			}
+			if got := testutil.ToFloat64(up); got != expectedUp {
+				t.Errorf("Expected up %f with reason %q, got %f", expectedUp, tt.expectedReason, got)
+			}
+
+			if tt.expectedReason == httpDownReasonTooManyRedirects {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if got := testutil.ToFloat64(collector.StatusCode.With(labels)); got != float64(tt.expectedStatus) {
+				t.Errorf("Expected status code %d, got %f", tt.expectedStatus, got)
+			}
+			if got := testutil.ToFloat64(collector.Redirects.With(labels)); got != float64(tt.expectedRedirects) {
+				t.Errorf("Expected %d redirects, got %f", tt.expectedRedirects, got)
+			}
+			finalURL := collector.FinalURL.MustCurryWith(labels).WithLabelValues(tt.expectedFinalURL)
+			if got := testutil.ToFloat64(finalURL); got != 1 {
+				t.Errorf("Expected final URL %s to be reported", tt.expectedFinalURL)
+			}
+		})
+	}
+}
+
+func TestHTTPTargetProvider_GetTargets_ClientSettings(t *testing.T) {
+	provider := HTTPTargetProvider{}
+	noFollow := false
+
+	targets, err := provider.GetTargets(&yamlconfig.YamlConfig{
+		HTTPStatusCode: []yamlconfig.HTTPMonitorDTO{
+			{URL: "https://example.com"},
+			{URL: "https://example.com", Timeout: 5, FollowRedirects: &noFollow, MaxRedirects: 2, DisableKeepAlives: true},
+		},
+	})
+	if err != nil {
+		t.Fatalf("GetTargets() returned unexpected error: %v", err)
+	}
+
+	defaults := targets[0]
+	if defaults.Timeout != defaultHTTPTimeout || !defaults.FollowRedirects || defaults.MaxRedirects != defaultHTTPMaxRedirects || defaults.DisableKeepAlives {
+		t.Errorf("Unexpected default client settings: %+v", defaults)
+	}
+
+	explicit := targets[1]
+	if explicit.Timeout != 5 || explicit.FollowRedirects || explicit.MaxRedirects != 2 || !explicit.DisableKeepAlives {
+		t.Errorf("Unexpected explicit client settings: %+v", explicit)
+	}
+
+	if _, err := provider.GetTargets(&yamlconfig.YamlConfig{
+		HTTPStatusCode: []yamlconfig.HTTPMonitorDTO{{URL: "https://example.com", Timeout: -1}},
+	}); err == nil {
+		t.Error("Expected error for negative timeout but got none")
+	}
+}
+
+func TestHTTPMonitorFactory_CreateMonitor_ClientSettings(t *testing.T) {
+	target := HTTPTarget{
+		Name:              "site",
+		URL:               "https://example.com",
+		Timeout:           7,
+		DisableKeepAlives: true,
+	}
+	monitor := HTTPMonitorFactory{}.CreateMonitor(target, HTTPMonitorFactory{}.CreateCollector(), log.New(bytes.NewBuffer(nil), "", 0))
+
+	client, ok := monitor.(*HTTPMonitor).Client.(*http.Client)
+	if !ok {
+		t.Fatal("Expected an *http.Client")
+	}
+	if client.Timeout != 7*time.Second {
+		t.Errorf("Expected timeout 7s, got %v", client.Timeout)
+	}
+	if client.CheckRedirect == nil {
+		t.Error("Expected a redirect policy")
+	}
+}
+
+func TestHTTPTargetProvider_GetTargets_IPProtocol(t *testing.T) {
+	provider := HTTPTargetProvider{}
+
+	targets, err := provider.GetTargets(&yamlconfig.YamlConfig{
+		HTTPStatusCode: []yamlconfig.HTTPMonitorDTO{
+			{URL: "https://example.com"},
+			{URL: "https://example.com", IPProtocol: "ipv6", ProbeAllAddresses: true},
+		},
+	})
+	if err != nil {
+		t.Fatalf("GetTargets() returned unexpected error: %v", err)
+	}
+
+	if targets[0].IPProtocol != ipProtocolBoth || targets[0].ProbeAllAddresses {
+		t.Errorf("Unexpected default IP settings: %+v", targets[0])
+	}
+	if targets[1].IPProtocol != ipProtocolIPv6 || !targets[1].ProbeAllAddresses {
+		t.Errorf("Unexpected explicit IP settings: %+v", targets[1])
+	}
+
+	if _, err := provider.GetTargets(&yamlconfig.YamlConfig{
+		HTTPStatusCode: []yamlconfig.HTTPMonitorDTO{{URL: "https://example.com", IPProtocol: "ipx"}},
+	}); err == nil {
+		t.Error("Expected error for invalid ip_protocol but got none")
+	}
+}
+
+func TestHTTPIPProtocolNetworks(t *testing.T) {
+	tests := []struct {
+		ipProtocol    string
+		expectedDial  string
+		expectedIPNet string
+	}{
+		{ipProtocol: ipProtocolIPv4, expectedDial: "tcp4", expectedIPNet: "ip4"},
+		{ipProtocol: ipProtocolIPv6, expectedDial: "tcp6", expectedIPNet: "ip6"},
+		{ipProtocol: ipProtocolBoth, expectedDial: "tcp", expectedIPNet: "ip"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ipProtocol, func(t *testing.T) {
+			if got := dialNetwork(tt.ipProtocol); got != tt.expectedDial {
+				t.Errorf("dialNetwork() = %s, expected %s", got, tt.expectedDial)
+			}
+			if got := lookupNetwork(tt.ipProtocol); got != tt.expectedIPNet {
+				t.Errorf("lookupNetwork() = %s, expected %s", got, tt.expectedIPNet)
+			}
+		})
+	}
+}
+
+func TestHTTPMonitor_Run_ProbeAllAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {}))
+	defer server.Close()
+
+	// The server only listens on 127.0.0.1, connections to 127.0.0.2 are
+	// refused.
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+	targetURL := "http://labtime.test:" + port
+	addresses := []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("127.0.0.2")}
+
+	collector := HTTPMonitorFactory{}.CreateCollector()
+	target := HTTPTarget{
+		Name:              "site",
+		URL:               targetURL,
+		Method:            http.MethodGet,
+		Timeout:           5,
+		FollowRedirects:   true,
+		IPProtocol:        ipProtocolIPv4,
+		ProbeAllAddresses: true,
+	}
+	monitor, ok := HTTPMonitorFactory{}.CreateMonitor(target, collector, log.New(bytes.NewBuffer(nil), "", 0)).(*HTTPMonitor)
+	if !ok {
+		t.Fatal("Expected an *HTTPMonitor")
+	}
+	var lookupNetwork string
+	monitor.LookupIP = func(_ context.Context, network, host string) ([]net.IP, error) {
+		if host != "labtime.test" {
+			t.Errorf("Unexpected lookup of %s", host)
+		}
+		lookupNetwork = network
+		return addresses, nil
+	}
+
+	if err := monitor.Run(t.Context()); err == nil {
+		t.Error("Expected error for the refused address but got none")
+	}
+	if lookupNetwork != "ip4" {
+		t.Errorf("Expected lookup network ip4, got %s", lookupNetwork)
+	}
+
+	labels := func(address string) prometheus.Labels {
+		return prometheus.Labels{
+			"http_monitor_site_name": "site",
+			"http_site_url":          targetURL,
+			"address":                address,
+			"proxy":                  "",
+		}
+	}
+	if got := testutil.ToFloat64(collector.Up.MustCurryWith(labels("127.0.0.1")).WithLabelValues("")); got != 1 {
+		t.Errorf("Expected 127.0.0.1 to be up, got %f", got)
+	}
+	if got := testutil.ToFloat64(collector.StatusCode.With(labels("127.0.0.1"))); got != http.StatusOK {
+		t.Errorf("Expected status code 200 for 127.0.0.1, got %f", got)
+	}
+	if got := testutil.ToFloat64(collector.Up.MustCurryWith(labels("127.0.0.2")).WithLabelValues(httpDownReasonConnectionRefused)); got != 0 {
+		t.Errorf("Expected 127.0.0.2 to be down, got %f", got)
+	}
+
+	// Addresses that are no longer resolved are removed.
+	addresses = addresses[:1]
+	if err := monitor.Run(t.Context()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if count := testutil.CollectAndCount(collector, "labtime_http_up"); count != 1 {
+		t.Errorf("Expected 1 up series, got %d", count)
+	}
+}
+
+func TestParseUnixSocketURL(t *testing.T) {
+	tests := []struct {
+		url                string
+		expectedSocketPath string
+		expectedRequestURL string
+		expectError        bool
+	}{
+		{
+			url:                "unix:///run/app.sock:/health",
+			expectedSocketPath: "/run/app.sock",
+			expectedRequestURL: "http://localhost/health",
+		},
+		{
+			url:                "unix:///var/run/docker.sock:/v1.47/_ping?verbose=1",
+			expectedSocketPath: "/var/run/docker.sock",
+			expectedRequestURL: "http://localhost/v1.47/_ping?verbose=1",
+		},
+		{
+			url:                "unix:///run/app.sock",
+			expectedSocketPath: "/run/app.sock",
+			expectedRequestURL: "http://localhost/",
+		},
+		{url: "unix://:/health", expectError: true},
+		{url: "unix:///run/app.sock:health", expectError: true},
+		{url: "http://example.com", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			socketPath, requestURL, err := parseUnixSocketURL(tt.url)
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if socketPath != tt.expectedSocketPath || requestURL != tt.expectedRequestURL {
+				t.Errorf("Expected (%s, %s), got (%s, %s)", tt.expectedSocketPath, tt.expectedRequestURL, socketPath, requestURL)
+			}
+		})
+	}
+}
+
+func TestHTTPMonitor_Run_UnixSocket(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "app.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("Failed to listen on unix socket: %v", err)
+	}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	targetURL := "unix://" + socketPath + ":/health"
+	targets, err := HTTPTargetProvider{}.GetTargets(&yamlconfig.YamlConfig{
+		HTTPStatusCode: []yamlconfig.HTTPMonitorDTO{{Name: "socket", URL: targetURL, Method: http.MethodGet}},
+	})
+	if err != nil {
+		t.Fatalf("GetTargets() returned unexpected error: %v", err)
+	}
+
+	collector := HTTPMonitorFactory{}.CreateCollector()
+	monitor := HTTPMonitorFactory{}.CreateMonitor(targets[0], collector, log.New(bytes.NewBuffer(nil), "", 0))
+	if err := monitor.Run(t.Context()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	labels := prometheus.Labels{
+		"http_monitor_site_name": "socket",
+		"http_site_url":          targetURL,
+		"address":                "",
+		"proxy":                  "",
+	}
+	if got := testutil.ToFloat64(collector.StatusCode.With(labels)); got != http.StatusOK {
+		t.Errorf("Expected status code 200, got %f", got)
+	}
+	if got := testutil.ToFloat64(collector.FinalURL.MustCurryWith(labels).WithLabelValues(targetURL)); got != 1 {
+		t.Errorf("Expected final URL %s, got %f", targetURL, got)
+	}
+}
@@ -30,3 +30,21 @@ type MonitorFactory[T Target, C prometheus.Collector] interface {
 type TargetProvider[T Target] interface {
 	GetTargets(config *yamlconfig.YamlConfig) ([]T, error)
 }
+
+// multiCollector groups the metrics exported by the monitors of a kind. The
+// collectors of the monitors embed it to implement prometheus.Collector.
+type multiCollector []prometheus.Collector
+
+// Describe implements the prometheus.Collector interface.
+func (m multiCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, collector := range m {
+		collector.Describe(ch)
+	}
+}
+
+// Collect implements the prometheus.Collector interface.
+func (m multiCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, collector := range m {
+		collector.Collect(ch)
+	}
+}
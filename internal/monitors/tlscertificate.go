@@ -0,0 +1,47 @@
+package monitors
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"strings"
+)
+
+// certificateLabelNames are the labels identifying a certificate.
+var certificateLabelNames = []string{"serial", "issuer_cn", "subject_cn", "sans"}
+
+// certificateLabelValues returns the values of the certificate labels, in the
+// order of certificateLabelNames.
+func certificateLabelValues(cert *x509.Certificate) []string {
+	return []string{
+		cert.SerialNumber.Text(16),
+		cert.Issuer.CommonName,
+		cert.Subject.CommonName,
+		certificateSANs(cert),
+	}
+}
+
+// certificateSANs returns the comma separated DNS names and IP addresses of
+// the certificate.
+func certificateSANs(cert *x509.Certificate) string {
+	sans := append([]string{}, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	return strings.Join(sans, ",")
+}
+
+// tlsVersionName returns the name of the TLS version, e.g. "TLS 1.3".
+func tlsVersionName(version uint16) string {
+	if version == 0 {
+		return ""
+	}
+	return tls.VersionName(version)
+}
+
+// cipherSuiteName returns the name of the cipher suite.
+func cipherSuiteName(id uint16) string {
+	if id == 0 {
+		return ""
+	}
+	return tls.CipherSuiteName(id)
+}
@@ -0,0 +1,80 @@
+package monitors
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Pins reported by the labtime_tls_pin_mismatch metric.
+const (
+	tlsPinFingerprint = "fingerprint"
+	tlsPinPublicKey   = "public_key"
+)
+
+// certificateFingerprint returns the hex encoded SHA-256 fingerprint of the
+// certificate.
+func certificateFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// TLSPin holds the expected SHA-256 hashes of the leaf certificate and of its
+// public key (SubjectPublicKeyInfo). A nil hash is not checked.
+type TLSPin struct {
+	Fingerprint []byte
+	PublicKey   []byte
+}
+
+// newTLSPin parses the pinned hashes of a target. It returns nil when nothing
+// is pinned.
+func newTLSPin(fingerprint, publicKey string) (*TLSPin, error) {
+	if fingerprint == "" && publicKey == "" {
+		return nil, nil //nolint:nilnil // no pin is not an error
+	}
+
+	pin := &TLSPin{}
+	var err error
+	if fingerprint != "" {
+		if pin.Fingerprint, err = parseSHA256(fingerprint); err != nil {
+			return nil, errors.Wrap(err, "invalid pinned fingerprint")
+		}
+	}
+	if publicKey != "" {
+		if pin.PublicKey, err = parseSHA256(publicKey); err != nil {
+			return nil, errors.Wrap(err, "invalid pinned public key")
+		}
+	}
+	return pin, nil
+}
+
+// parseSHA256 decodes a SHA-256 hash, hex encoded (colons allowed, as printed
+// by openssl) or base64 encoded (as in HPKP pins).
+func parseSHA256(s string) ([]byte, error) {
+	if sum, err := hex.DecodeString(strings.ReplaceAll(s, ":", "")); err == nil && len(sum) == sha256.Size {
+		return sum, nil
+	}
+	if sum, err := base64.StdEncoding.DecodeString(s); err == nil && len(sum) == sha256.Size {
+		return sum, nil
+	}
+	return nil, errors.Errorf("%q is not a hex or base64 encoded SHA-256 hash", s)
+}
+
+// mismatches tells whether the certificate differs from each pinned hash.
+func (p *TLSPin) mismatches(cert *x509.Certificate) map[string]bool {
+	mismatches := map[string]bool{}
+	if p.Fingerprint != nil {
+		sum := sha256.Sum256(cert.Raw)
+		mismatches[tlsPinFingerprint] = !bytes.Equal(sum[:], p.Fingerprint)
+	}
+	if p.PublicKey != nil {
+		sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+		mismatches[tlsPinPublicKey] = !bytes.Equal(sum[:], p.PublicKey)
+	}
+	return mismatches
+}
@@ -0,0 +1,77 @@
+package monitors
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestParseSHA256(t *testing.T) {
+	sum := sha256.Sum256([]byte("labtime"))
+	colons := strings.ToUpper(hex.EncodeToString(sum[:]))
+	for i := len(colons) - 2; i > 0; i -= 2 {
+		colons = colons[:i] + ":" + colons[i:]
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{name: "hex", input: hex.EncodeToString(sum[:])},
+		{name: "hex with colons", input: colons},
+		{name: "base64", input: base64.StdEncoding.EncodeToString(sum[:])},
+		{name: "too short", input: hex.EncodeToString(sum[:16]), wantErr: true},
+		{name: "garbage", input: "not a hash", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSHA256(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSHA256() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && string(got) != string(sum[:]) {
+				t.Errorf("parseSHA256() = %x, want %x", got, sum)
+			}
+		})
+	}
+}
+
+func TestNewTLSPin(t *testing.T) {
+	pin, err := newTLSPin("", "")
+	if err != nil || pin != nil {
+		t.Errorf("newTLSPin() = %v, %v, want nil, nil", pin, err)
+	}
+
+	if _, err := newTLSPin("invalid", ""); err == nil {
+		t.Error("newTLSPin() with an invalid fingerprint should fail")
+	}
+	if _, err := newTLSPin("", "invalid"); err == nil {
+		t.Error("newTLSPin() with an invalid public key should fail")
+	}
+}
+
+func TestTLSPin_mismatches(t *testing.T) {
+	cert := newTestCertificate(t, &x509.Certificate{}, nil)
+	other := newTestCertificate(t, &x509.Certificate{}, nil)
+	publicKey := sha256.Sum256(cert.Cert.RawSubjectPublicKeyInfo)
+
+	pin, err := newTLSPin(certificateFingerprint(cert.Cert), base64.StdEncoding.EncodeToString(publicKey[:]))
+	if err != nil {
+		t.Fatalf("newTLSPin() error = %v", err)
+	}
+
+	got := pin.mismatches(cert.Cert)
+	if got[tlsPinFingerprint] || got[tlsPinPublicKey] {
+		t.Errorf("mismatches() = %v, want no mismatch", got)
+	}
+
+	got = pin.mismatches(other.Cert)
+	if !got[tlsPinFingerprint] || !got[tlsPinPublicKey] {
+		t.Errorf("mismatches() = %v, want both mismatches", got)
+	}
+}
@@ -3,7 +3,12 @@ package monitors
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
 	"time"
 
 	"aireone.xyz/labtime/internal/yamlconfig"
@@ -11,13 +16,45 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 )
 
-type TLSDialFunc func(network, addr string, config *tls.Config) (*tls.Conn, error)
+// TLSDialFunc opens the TLS connections and performs the handshake. It must
+// give up when the context is done.
+type TLSDialFunc func(ctx context.Context, network, addr string, config *tls.Config) (*tls.Conn, error)
+
+// NetDialFunc opens the plain text connections upgraded with STARTTLS. It
+// must give up when the context is done.
+type NetDialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+const (
+	// defaultTLSPort is the port of the TLS targets when none is configured.
+	defaultTLSPort = 443
+	// defaultTLSTimeout is the default handshake timeout in seconds.
+	defaultTLSTimeout = 10
+)
+
+// dialTLS is the default TLSDialFunc, a tls.Dialer using the configuration.
+func dialTLS(ctx context.Context, network, addr string, config *tls.Config) (*tls.Conn, error) {
+	dialer := &tls.Dialer{Config: config}
+	conn, err := dialer.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	return conn.(*tls.Conn), nil //nolint:forcetypeassert // tls.Dialer always returns a *tls.Conn
+}
 
 // TLSTarget represents a TLS monitoring target.
 type TLSTarget struct {
-	Name     string `yaml:"name"`
-	Domain   string `yaml:"domain"`
-	Interval int    `yaml:"interval,omitempty"`
+	Name       string     `yaml:"name"`
+	Domain     string     `yaml:"domain"`
+	Port       int        `yaml:"port,omitempty"`
+	Address    string     `yaml:"address,omitempty"`
+	ServerName string     `yaml:"server_name,omitempty"`
+	StartTLS   string     `yaml:"starttls,omitempty"`
+	OCSP       bool       `yaml:"ocsp,omitempty"`
+	CRL        bool       `yaml:"crl,omitempty"`
+	Policy     *TLSPolicy `yaml:"policy,omitempty"`
+	Pin        *TLSPin    `yaml:"pin,omitempty"`
+	Timeout    int        `yaml:"timeout,omitempty"`
+	Interval   int        `yaml:"interval,omitempty"`
 }
 
 // GetName implements the Target interface.
@@ -33,22 +70,106 @@ func (t TLSTarget) GetInterval() int {
 // TLSMonitorFactory implements MonitorFactory for TLS monitoring.
 type TLSMonitorFactory struct{}
 
-// CreateCollector creates a Prometheus GaugeVec for TLS monitoring.
-func (t TLSMonitorFactory) CreateCollector() *prometheus.GaugeVec {
-	return prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "labtime_tls_cert_expire_time",
-		Help: "The duration (in second) until the TLS certificate expires.",
-	}, []string{"tls_monitor_name", "tls_domain_name"})
+// TLSCollector groups the Prometheus metrics exported by the TLS monitors.
+type TLSCollector struct {
+	ExpiresTime    *prometheus.GaugeVec
+	CertNotAfter   *prometheus.GaugeVec
+	CertNotBefore  *prometheus.GaugeVec
+	ConnectionInfo *prometheus.GaugeVec
+	Valid          *prometheus.GaugeVec
+	Revoked        *prometheus.GaugeVec
+	OCSPStapled    *prometheus.GaugeVec
+	Violation      *prometheus.GaugeVec
+	Fingerprint    *prometheus.GaugeVec
+	CertChanged    *prometheus.GaugeVec
+	PinMismatch    *prometheus.GaugeVec
+	Handshake      *prometheus.GaugeVec
+
+	multiCollector
+}
+
+// CreateCollector creates the Prometheus collectors for TLS monitoring.
+func (t TLSMonitorFactory) CreateCollector() *TLSCollector {
+	labels := []string{"tls_monitor_name", "tls_domain_name"}
+	certLabels := append(append([]string{}, labels...), certificateLabelNames...)
+	c := &TLSCollector{
+		ExpiresTime: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "labtime_tls_cert_expire_time",
+			Help: "The duration (in second) until the TLS certificate expires.",
+		}, labels),
+		CertNotAfter: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "labtime_tls_cert_not_after",
+			Help: "The expiration timestamp (in seconds since epoch) of each certificate of the chain presented by the server.",
+		}, certLabels),
+		CertNotBefore: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "labtime_tls_cert_not_before",
+			Help: "The start of validity timestamp (in seconds since epoch) of each certificate of the chain presented by the server.",
+		}, certLabels),
+		ConnectionInfo: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "labtime_tls_connection_info",
+			Help: "The TLS version and cipher suite negotiated with the server. The value is always 1.",
+		}, append(labels, "tls_version", "cipher_suite")),
+		Valid: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "labtime_tls_cert_valid",
+			Help: "Whether the certificate chain is valid for the server name (1 = valid, 0 = invalid). The reason label explains why the chain is invalid.",
+		}, append(labels, "reason")),
+		Revoked: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "labtime_tls_cert_revoked",
+			Help: "Whether the certificate is revoked (1 = revoked, 0 = not revoked) according to the method (ocsp or crl).",
+		}, append(labels, "method")),
+		OCSPStapled: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "labtime_tls_ocsp_staple_present",
+			Help: "Whether the server staples an OCSP response in the handshake (1 = present, 0 = absent).",
+		}, labels),
+		Violation: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "labtime_tls_policy_violation",
+			Help: "Whether the service violates the policy rule (1 = violation, 0 = compliant).",
+		}, append(labels, "rule")),
+		Fingerprint: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "labtime_tls_cert_fingerprint_info",
+			Help: "The SHA-256 fingerprint of the leaf certificate presented by the server. The value is always 1.",
+		}, append(labels, "fingerprint")),
+		CertChanged: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "labtime_tls_cert_changed_timestamp",
+			Help: "The timestamp (in seconds since epoch) at which the leaf certificate fingerprint was first seen or last changed.",
+		}, labels),
+		PinMismatch: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "labtime_tls_pin_mismatch",
+			Help: "Whether the leaf certificate differs from the pinned hash (1 = mismatch, 0 = match) according to the pin (fingerprint or public_key).",
+		}, append(labels, "pin")),
+		Handshake: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "labtime_tls_handshake_duration_seconds",
+			Help: "The duration (in seconds) of the last TLS handshake, including the connection and the STARTTLS negotiation.",
+		}, labels),
+	}
+	c.multiCollector = multiCollector{
+		c.ExpiresTime, c.CertNotAfter, c.CertNotBefore, c.ConnectionInfo, c.Valid, c.Revoked, c.OCSPStapled,
+		c.Violation, c.Fingerprint, c.CertChanged, c.PinMismatch, c.Handshake,
+	}
+	return c
 }
 
 // CreateMonitor creates a TLS monitor instance.
-func (t TLSMonitorFactory) CreateMonitor(target TLSTarget, collector *prometheus.GaugeVec, logger *log.Logger) Job {
+func (t TLSMonitorFactory) CreateMonitor(target TLSTarget, collector *TLSCollector, logger *log.Logger) Job {
 	return &TLSMonitor{
-		Label:              target.Name,
-		Domain:             target.Domain,
-		Logger:             logger,
-		ExpiresTimeMonitor: collector,
-		DialFunc:           tls.Dial,
+		Label:       target.Name,
+		Domain:      target.Domain,
+		Port:        target.Port,
+		Address:     target.Address,
+		ServerName:  target.ServerName,
+		StartTLS:    target.StartTLS,
+		OCSP:        target.OCSP,
+		CRL:         target.CRL,
+		Policy:      target.Policy,
+		Pin:         target.Pin,
+		Timeout:     time.Duration(target.Timeout) * time.Second,
+		Logger:      logger,
+		Metrics:     collector,
+		DialFunc:    dialTLS,
+		NetDialFunc: (&net.Dialer{}).DialContext,
+		RevocationClient: &http.Client{
+			Timeout: defaultRevocationTimeout,
+		},
 	}
 }
 
@@ -67,10 +188,53 @@ func (t TLSTargetProvider) GetTargets(config *yamlconfig.YamlConfig) ([]TLSTarge
 		if interval == 0 {
 			interval = 60
 		}
+		timeout := monitor.Timeout
+		if timeout == 0 {
+			timeout = defaultTLSTimeout
+		} else if timeout < 0 {
+			return nil, errors.Errorf("invalid timeout %d for target '%s'", timeout, name)
+		}
+		if monitor.StartTLS != "" && !isValidStartTLS(monitor.StartTLS) {
+			return nil, errors.Errorf("invalid starttls '%s' for target '%s'", monitor.StartTLS, name)
+		}
+		port := monitor.Port
+		if port == 0 {
+			port = defaultTLSPort
+			if monitor.StartTLS != "" {
+				port = startTLSDefaultPorts[monitor.StartTLS]
+			}
+		} else if port < 0 || port > 65535 {
+			return nil, errors.Errorf("invalid port %d for target '%s'", port, name)
+		}
+		address := monitor.Address
+		if address == "" {
+			address = monitor.Domain
+		}
+		serverName := monitor.ServerName
+		if serverName == "" {
+			serverName = monitor.Domain
+		}
+		policy, err := newTLSPolicy(monitor.Policy)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid policy for target '%s'", name)
+		}
+		pin, err := newTLSPin(monitor.PinnedFingerprint, monitor.PinnedPublicKey)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid pin for target '%s'", name)
+		}
 		targets[i] = TLSTarget{
-			Name:     name,
-			Domain:   monitor.Domain,
-			Interval: interval,
+			Name:       name,
+			Domain:     monitor.Domain,
+			Port:       port,
+			Address:    address,
+			ServerName: serverName,
+			StartTLS:   monitor.StartTLS,
+			OCSP:       monitor.OCSP,
+			CRL:        monitor.CRL,
+			Policy:     policy,
+			Pin:        pin,
+			Timeout:    timeout,
+			Interval:   interval,
 		}
 	}
 	return targets, nil
@@ -79,24 +243,64 @@ func (t TLSTargetProvider) GetTargets(config *yamlconfig.YamlConfig) ([]TLSTarge
 type TLSMonitor struct {
 	Label  string
 	Domain string
+	// Port, Address and ServerName default to 443 and the domain.
+	Port       int
+	Address    string
+	ServerName string
+	// StartTLS is the protocol used to upgrade a plain text connection before
+	// the handshake, empty for direct TLS.
+	StartTLS string
+	// RootCAs verifies the certificates, nil uses the system roots.
+	RootCAs *x509.CertPool
+	// OCSP and CRL enable the revocation checks of the leaf certificate.
+	OCSP bool
+	CRL  bool
+	// Policy is evaluated against the service when set.
+	Policy *TLSPolicy
+	// Pin is checked against the leaf certificate when set.
+	Pin *TLSPin
+	// Timeout bounds each handshake, including the connection and the
+	// STARTTLS negotiation. Zero only relies on the context.
+	Timeout time.Duration
 
 	Logger *log.Logger
 
-	ExpiresTimeMonitor *prometheus.GaugeVec
+	Metrics *TLSCollector
+
+	DialFunc    TLSDialFunc
+	NetDialFunc NetDialFunc
+	// RevocationClient fetches the OCSP responses and CRLs.
+	RevocationClient HTTPClient
 
-	DialFunc TLSDialFunc
+	// mu guards the fingerprint, the runs of a monitor can overlap.
+	mu sync.Mutex
+	// fingerprint is the leaf certificate fingerprint of the previous run.
+	fingerprint string
 }
 
 func (t *TLSMonitor) ID() string {
 	return t.Label
 }
 
-func (t *TLSMonitor) Run(_ context.Context) error {
-	d, err := t.tlsHandshake()
+func (t *TLSMonitor) Run(ctx context.Context) error {
+	d, err := t.tlsHandshake(ctx)
 	if err != nil {
 		return errors.Wrap(err, "error running tls handshake")
 	}
 
+	if t.OCSP {
+		d.Revocations = append(d.Revocations, checkOCSP(ctx, t.RevocationClient, d.Certificates, d.OCSPStaple))
+	}
+	if t.CRL {
+		d.Revocations = append(d.Revocations, checkCRL(ctx, t.RevocationClient, d.Certificates))
+	}
+	if t.Policy != nil {
+		d.PolicyViolations = t.evaluatePolicy(ctx, d.Certificates)
+	}
+	if t.Pin != nil {
+		d.PinMismatches = t.Pin.mismatches(d.Certificates[0])
+	}
+
 	t.pushToPrometheus(d)
 
 	return nil
@@ -104,31 +308,252 @@ func (t *TLSMonitor) Run(_ context.Context) error {
 
 type TLSHealthCheckerData struct {
 	Expires time.Time
+	// HandshakeDuration includes the connection and the STARTTLS negotiation.
+	HandshakeDuration time.Duration
+	// Certificates is the chain presented by the server, leaf first.
+	Certificates []*x509.Certificate
+	Version      uint16
+	CipherSuite  uint16
+	// VerifyErr is the certificate verification error, nil when the chain is
+	// valid.
+	VerifyErr error
+	// OCSPStaple is the OCSP response stapled by the server, if any.
+	OCSPStaple  []byte
+	Revocations []RevocationResult
+	// PolicyViolations tells whether each policy rule is violated.
+	PolicyViolations map[string]bool
+	// PinMismatches tells whether the leaf certificate differs from each
+	// pinned hash.
+	PinMismatches map[string]bool
 }
 
-func (t *TLSMonitor) tlsHandshake() (*TLSHealthCheckerData, error) {
-	conn, err := t.DialFunc("tcp", t.Domain+":443", nil)
+func (t *TLSMonitor) tlsHandshake(ctx context.Context) (*TLSHealthCheckerData, error) {
+	start := time.Now()
+	conn, err := t.dial(ctx, t.tlsConfig())
 	if err != nil {
 		return nil, err
 	}
 	defer conn.Close()
+	duration := time.Since(start)
 
-	expires := conn.ConnectionState().PeerCertificates[0].NotAfter
+	state := conn.ConnectionState()
+	if len(state.PeerCertificates) == 0 {
+		return nil, errors.New("no certificate presented by the server")
+	}
+	expires := state.PeerCertificates[0].NotAfter
 	t.Logger.Printf("TLS certificate expires on %s", expires)
 
 	return &TLSHealthCheckerData{
-		Expires: expires,
+		Expires:           expires,
+		HandshakeDuration: duration,
+		Certificates:      state.PeerCertificates,
+		Version:           state.Version,
+		CipherSuite:       state.CipherSuite,
+		OCSPStaple:        state.OCSPResponse,
+		VerifyErr:         verifyCertificates(state.PeerCertificates, t.RootCAs, t.serverName(), time.Now()),
 	}, nil
 }
 
+// dial opens the TLS connection, upgrading a plain text connection when
+// STARTTLS is configured. It gives up after the timeout or when the context
+// is done.
+func (t *TLSMonitor) dial(ctx context.Context, config *tls.Config) (*tls.Conn, error) {
+	if t.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.Timeout)
+		defer cancel()
+	}
+
+	if t.StartTLS == "" {
+		return t.DialFunc(ctx, "tcp", t.addr(), config)
+	}
+
+	conn, err := t.NetDialFunc(ctx, "tcp", t.addr())
+	if err != nil {
+		return nil, err
+	}
+
+	// The STARTTLS negotiation is not context aware, the pending reads and
+	// writes are interrupted when the context is done.
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	if err := startTLS(conn, t.StartTLS, t.Domain); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, errors.Wrap(err, "error during tls handshake")
+	}
+	if !stop() {
+		conn.Close()
+		return nil, ctx.Err()
+	}
+	return tlsConn, nil
+}
+
+// evaluatePolicy evaluates the policy rules, probing the legacy TLS versions
+// when enabled.
+func (t *TLSMonitor) evaluatePolicy(ctx context.Context, certs []*x509.Certificate) map[string]bool {
+	violations := t.Policy.evaluateCertificates(certs)
+	if !t.Policy.ProbeLegacyVersions {
+		return violations
+	}
+
+	for _, legacy := range legacyTLSVersions {
+		config := t.tlsConfig()
+		config.MinVersion = legacy.Version //nolint:gosec // deprecated versions are probed on purpose
+		config.MaxVersion = legacy.Version
+		conn, err := t.dial(ctx, config)
+		violations[legacy.Rule] = err == nil
+		if err == nil {
+			conn.Close()
+		}
+	}
+	return violations
+}
+
+// addr returns the network address of the TLS service.
+func (t *TLSMonitor) addr() string {
+	host := t.Address
+	if host == "" {
+		host = t.Domain
+	}
+	port := t.Port
+	if port == 0 {
+		port = defaultTLSPort
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+// serverName returns the name sent in the handshake and verified against the
+// certificate.
+func (t *TLSMonitor) serverName() string {
+	if t.ServerName == "" {
+		return t.Domain
+	}
+	return t.ServerName
+}
+
+// tlsConfig returns the TLS client configuration of the handshake. The
+// verification is deferred so the certificates of invalid chains are still
+// inspected, see verifyCertificates.
+func (t *TLSMonitor) tlsConfig() *tls.Config {
+	return &tls.Config{
+		ServerName:         t.serverName(),
+		InsecureSkipVerify: true, //nolint:gosec // the chain is verified after the handshake
+		MinVersion:         tls.VersionTLS12,
+	}
+}
+
+func (t *TLSMonitor) labels() prometheus.Labels {
+	return prometheus.Labels{
+		"tls_monitor_name": t.Label,
+		"tls_domain_name":  t.Domain,
+	}
+}
+
 func (t *TLSMonitor) pushToPrometheus(d *TLSHealthCheckerData) {
 	remainingTime := time.Until(d.Expires).Seconds()
 	t.Logger.Printf("TLS certificate for monitor %s expires in %f seconds", t.Label, remainingTime)
 
-	t.ExpiresTimeMonitor.
-		With(prometheus.Labels{
-			"tls_monitor_name": t.Label,
-			"tls_domain_name":  t.Domain,
-		}).
-		Set(remainingTime)
+	labels := t.labels()
+	t.Metrics.ExpiresTime.With(labels).Set(remainingTime)
+	t.Metrics.Handshake.With(labels).Set(d.HandshakeDuration.Seconds())
+
+	// The chain can change between two checks (e.g. renewal), previous
+	// certificates are removed.
+	t.Metrics.CertNotAfter.DeletePartialMatch(labels)
+	t.Metrics.CertNotBefore.DeletePartialMatch(labels)
+	for _, cert := range d.Certificates {
+		values := certificateLabelValues(cert)
+		t.Metrics.CertNotAfter.MustCurryWith(labels).WithLabelValues(values...).Set(float64(cert.NotAfter.Unix()))
+		t.Metrics.CertNotBefore.MustCurryWith(labels).WithLabelValues(values...).Set(float64(cert.NotBefore.Unix()))
+	}
+
+	t.Metrics.Valid.DeletePartialMatch(labels)
+	if d.VerifyErr != nil {
+		reason := tlsInvalidReason(d.VerifyErr, time.Now())
+		t.Logger.Printf("TLS certificate for monitor %s is invalid (%s): %v", t.Label, reason, d.VerifyErr)
+		t.Metrics.Valid.MustCurryWith(labels).WithLabelValues(reason).Set(0)
+	} else {
+		t.Metrics.Valid.MustCurryWith(labels).WithLabelValues("").Set(1)
+	}
+
+	stapled := 0.0
+	if len(d.OCSPStaple) > 0 {
+		stapled = 1
+	}
+	t.Metrics.OCSPStapled.With(labels).Set(stapled)
+
+	for _, r := range d.Revocations {
+		revoked := t.Metrics.Revoked.MustCurryWith(labels)
+		if r.Err != nil {
+			t.Logger.Printf("TLS %s revocation check for monitor %s failed: %v", r.Method, t.Label, r.Err)
+			revoked.DeleteLabelValues(r.Method)
+			continue
+		}
+		value := 0.0
+		if r.Revoked {
+			t.Logger.Printf("TLS certificate for monitor %s is revoked (%s)", t.Label, r.Method)
+			value = 1
+		}
+		revoked.WithLabelValues(r.Method).Set(value)
+	}
+
+	for rule, violated := range d.PolicyViolations {
+		value := 0.0
+		if violated {
+			t.Logger.Printf("TLS policy rule %s violated for monitor %s", rule, t.Label)
+			value = 1
+		}
+		t.Metrics.Violation.MustCurryWith(labels).WithLabelValues(rule).Set(value)
+	}
+
+	if len(d.Certificates) > 0 {
+		t.recordFingerprint(certificateFingerprint(d.Certificates[0]))
+	}
+
+	for pin, mismatch := range d.PinMismatches {
+		value := 0.0
+		if mismatch {
+			t.Logger.Printf("TLS certificate for monitor %s does not match the pinned %s", t.Label, pin)
+			value = 1
+		}
+		t.Metrics.PinMismatch.MustCurryWith(labels).WithLabelValues(pin).Set(value)
+	}
+
+	if d.Version != 0 {
+		t.Metrics.ConnectionInfo.DeletePartialMatch(labels)
+		t.Metrics.ConnectionInfo.
+			MustCurryWith(labels).
+			WithLabelValues(tlsVersionName(d.Version), cipherSuiteName(d.CipherSuite)).
+			Set(1)
+	}
+}
+
+// recordFingerprint exports the leaf certificate fingerprint and updates the
+// change timestamp when it differs from the previous run.
+func (t *TLSMonitor) recordFingerprint(fingerprint string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if fingerprint == t.fingerprint {
+		return
+	}
+
+	if t.fingerprint != "" {
+		t.Logger.Printf("TLS certificate for monitor %s changed: %s -> %s", t.Label, t.fingerprint, fingerprint)
+	}
+	t.fingerprint = fingerprint
+
+	labels := t.labels()
+	t.Metrics.Fingerprint.DeletePartialMatch(labels)
+	t.Metrics.Fingerprint.MustCurryWith(labels).WithLabelValues(fingerprint).Set(1)
+	t.Metrics.CertChanged.With(labels).SetToCurrentTime()
 }
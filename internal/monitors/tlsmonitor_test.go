@@ -2,9 +2,16 @@ package monitors
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"errors"
+	"io"
 	"log"
+	"net"
+	"strconv"
+	"sync"
 	"testing"
 	"time"
 
@@ -57,7 +64,7 @@ func TestTLSMonitorFactory_CreateCollector(t *testing.T) {
 	}
 
 	// Test that the collector accepts the expected label structure without panicking
-	gauge := collector.With(prometheus.Labels{
+	gauge := collector.ExpiresTime.With(prometheus.Labels{
 		"tls_monitor_name": "test-domain",
 		"tls_domain_name":  "example.com",
 	})
@@ -101,8 +108,8 @@ func TestTLSMonitorFactory_CreateMonitor(t *testing.T) {
 		t.Error("Logger was not set correctly")
 	}
 
-	if tlsMonitor.ExpiresTimeMonitor != collector {
-		t.Error("ExpiresTimeMonitor was not set correctly")
+	if tlsMonitor.Metrics != collector {
+		t.Error("Metrics was not set correctly")
 	}
 }
 
@@ -169,6 +176,61 @@ func TestTLSTargetProvider_GetTargets(t *testing.T) {
 	}
 }
 
+func TestTLSTargetProvider_GetTargets_Endpoint(t *testing.T) {
+	provider := TLSTargetProvider{}
+
+	targets, err := provider.GetTargets(&yamlconfig.YamlConfig{
+		TLSMonitors: []yamlconfig.TLSMonitorDTO{
+			{Domain: "example.com"},
+			{Domain: "example.com", Port: 6443, Address: "192.0.2.10", ServerName: "kubernetes.default"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("GetTargets() returned unexpected error: %v", err)
+	}
+
+	defaults := targets[0]
+	if defaults.Port != 443 || defaults.Address != "example.com" || defaults.ServerName != "example.com" {
+		t.Errorf("Unexpected default endpoint: %+v", defaults)
+	}
+	explicit := targets[1]
+	if explicit.Port != 6443 || explicit.Address != "192.0.2.10" || explicit.ServerName != "kubernetes.default" {
+		t.Errorf("Unexpected explicit endpoint: %+v", explicit)
+	}
+
+	if _, err := provider.GetTargets(&yamlconfig.YamlConfig{
+		TLSMonitors: []yamlconfig.TLSMonitorDTO{{Domain: "example.com", Port: 70000}},
+	}); err == nil {
+		t.Error("Expected error for invalid port but got none")
+	}
+}
+
+func TestTLSTargetProvider_GetTargets_Timeout(t *testing.T) {
+	provider := TLSTargetProvider{}
+
+	targets, err := provider.GetTargets(&yamlconfig.YamlConfig{
+		TLSMonitors: []yamlconfig.TLSMonitorDTO{
+			{Domain: "example.com"},
+			{Domain: "example.com", Timeout: 3},
+		},
+	})
+	if err != nil {
+		t.Fatalf("GetTargets() returned unexpected error: %v", err)
+	}
+	if targets[0].Timeout != defaultTLSTimeout {
+		t.Errorf("Expected default timeout %d, got %d", defaultTLSTimeout, targets[0].Timeout)
+	}
+	if targets[1].Timeout != 3 {
+		t.Errorf("Expected timeout 3, got %d", targets[1].Timeout)
+	}
+
+	if _, err := provider.GetTargets(&yamlconfig.YamlConfig{
+		TLSMonitors: []yamlconfig.TLSMonitorDTO{{Domain: "example.com", Timeout: -1}},
+	}); err == nil {
+		t.Error("Expected error for invalid timeout but got none")
+	}
+}
+
 func TestTLSMonitor_ID(t *testing.T) {
 	const expectedID = "test-domain"
 
@@ -191,12 +253,12 @@ func TestTLSMonitor_tlsHandshake_DialError(t *testing.T) {
 		Label:  "test-domain",
 		Domain: "example.com",
 		Logger: logger,
-		DialFunc: func(_, _ string, _ *tls.Config) (*tls.Conn, error) {
+		DialFunc: func(_ context.Context, _, _ string, _ *tls.Config) (*tls.Conn, error) {
 			return nil, errors.New("connection failed")
 		},
 	}
 
-	data, err := monitor.tlsHandshake()
+	data, err := monitor.tlsHandshake(t.Context())
 
 	if err == nil {
 		t.Error("Expected error but got none")
@@ -207,15 +269,164 @@ func TestTLSMonitor_tlsHandshake_DialError(t *testing.T) {
 	}
 }
 
+// newTestTLSListener starts a TLS server serving the certificate and returns
+// its address. The server performs the handshake and closes the connections.
+func newTestTLSListener(t *testing.T, certificate tls.Certificate) string {
+	t.Helper()
+
+	return newTestTLSListenerWithConfig(t, &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   tls.VersionTLS12,
+	})
+}
+
+// newTestTLSListenerWithConfig starts a TLS server with the configuration
+// and returns its address.
+func newTestTLSListenerWithConfig(t *testing.T, config *tls.Config) string {
+	t.Helper()
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", config)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.(*tls.Conn).Handshake()
+			_ = conn.Close()
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+// newSilentListener starts a TCP server accepting the connections without
+// ever answering and returns its address.
+func newSilentListener(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { _ = conn.Close() })
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+func TestTLSMonitor_tlsHandshake_Timeout(t *testing.T) {
+	host, port, _ := net.SplitHostPort(newSilentListener(t))
+
+	for _, startTLS := range []string{"", startTLSSMTP} {
+		t.Run("starttls="+startTLS, func(t *testing.T) {
+			factory := TLSMonitorFactory{}
+			monitor := factory.CreateMonitor(TLSTarget{
+				Name:     "silent",
+				Domain:   host,
+				StartTLS: startTLS,
+			}, factory.CreateCollector(), log.New(bytes.NewBuffer(nil), "", 0)).(*TLSMonitor)
+			monitor.Port, _ = strconv.Atoi(port)
+			monitor.Timeout = 100 * time.Millisecond
+
+			start := time.Now()
+			if _, err := monitor.tlsHandshake(t.Context()); err == nil {
+				t.Fatal("Expected timeout error but got none")
+			}
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("Expected the handshake to give up after the timeout, took %s", elapsed)
+			}
+		})
+	}
+}
+
+func TestTLSMonitor_tlsHandshake_Canceled(t *testing.T) {
+	host, port, _ := net.SplitHostPort(newSilentListener(t))
+
+	factory := TLSMonitorFactory{}
+	monitor := factory.CreateMonitor(TLSTarget{
+		Name:     "silent",
+		Domain:   host,
+		StartTLS: startTLSSMTP,
+	}, factory.CreateCollector(), log.New(bytes.NewBuffer(nil), "", 0)).(*TLSMonitor)
+	monitor.Port, _ = strconv.Atoi(port)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	if err := monitor.Run(ctx); err == nil {
+		t.Fatal("Expected cancellation error but got none")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the handshake to stop on cancellation, took %s", elapsed)
+	}
+}
+
+func TestTLSMonitor_tlsHandshake_Endpoint(t *testing.T) {
+	ca := newTestCertificate(t, &x509.Certificate{IsCA: true}, nil)
+	notAfter := time.Now().Add(48 * time.Hour).Truncate(time.Second)
+	leaf := newTestCertificate(t, &x509.Certificate{DNSNames: []string{"internal.example.com"}, NotAfter: notAfter}, ca)
+	addr := newTestTLSListener(t, leaf.TLSCertificate())
+	host, port, _ := net.SplitHostPort(addr)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Cert)
+
+	var dialedAddr, serverName string
+	monitor := &TLSMonitor{
+		Label:      "backend",
+		Domain:     "example.com",
+		Address:    host,
+		ServerName: "internal.example.com",
+		RootCAs:    roots,
+		Logger:     log.New(bytes.NewBuffer(nil), "", 0),
+		DialFunc: func(ctx context.Context, network, addr string, config *tls.Config) (*tls.Conn, error) {
+			dialedAddr, serverName = addr, config.ServerName
+			return dialTLS(ctx, network, addr, config)
+		},
+	}
+	monitor.Port, _ = strconv.Atoi(port)
+
+	data, err := monitor.tlsHandshake(t.Context())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if dialedAddr != addr {
+		t.Errorf("Expected dial to %s, got %s", addr, dialedAddr)
+	}
+	if serverName != "internal.example.com" {
+		t.Errorf("Expected server name internal.example.com, got %s", serverName)
+	}
+	if !data.Expires.Equal(notAfter) {
+		t.Errorf("Expected expiration %s, got %s", notAfter, data.Expires)
+	}
+	if data.VerifyErr != nil {
+		t.Errorf("Unexpected verification error: %v", data.VerifyErr)
+	}
+}
+
 func TestTLSMonitor_pushToPrometheus(t *testing.T) {
 	const testLabel = "test-domain"
 	const testDomain = "example.com"
 
 	// Create collector and register it
-	collector := prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "labtime_tls_cert_expire_time",
-		Help: "The duration (in second) until the TLS certificate expires.",
-	}, []string{"tls_monitor_name", "tls_domain_name"})
+	collector := TLSMonitorFactory{}.CreateCollector()
 
 	reg := prometheus.NewRegistry()
 	reg.MustRegister(collector)
@@ -224,16 +435,17 @@ func TestTLSMonitor_pushToPrometheus(t *testing.T) {
 	logger := log.New(&logBuf, "", 0)
 
 	monitor := &TLSMonitor{
-		Label:              testLabel,
-		Domain:             testDomain,
-		Logger:             logger,
-		ExpiresTimeMonitor: collector,
+		Label:   testLabel,
+		Domain:  testDomain,
+		Logger:  logger,
+		Metrics: collector,
 	}
 
 	// Test with certificate expiring in 1 day
 	futureTime := time.Now().Add(24 * time.Hour)
 	data := &TLSHealthCheckerData{
-		Expires: futureTime,
+		Expires:           futureTime,
+		HandshakeDuration: 250 * time.Millisecond,
 	}
 
 	monitor.pushToPrometheus(data)
@@ -242,7 +454,7 @@ func TestTLSMonitor_pushToPrometheus(t *testing.T) {
 	expectedValue := time.Until(futureTime).Seconds()
 
 	// Allow for small timing differences (within 1 second)
-	metricValue := testutil.ToFloat64(collector.With(prometheus.Labels{
+	metricValue := testutil.ToFloat64(collector.ExpiresTime.With(prometheus.Labels{
 		"tls_monitor_name": testLabel,
 		"tls_domain_name":  testDomain,
 	}))
@@ -251,6 +463,14 @@ func TestTLSMonitor_pushToPrometheus(t *testing.T) {
 		t.Errorf("Expected metric value around %f, got %f", expectedValue, metricValue)
 	}
 
+	handshake := testutil.ToFloat64(collector.Handshake.With(prometheus.Labels{
+		"tls_monitor_name": testLabel,
+		"tls_domain_name":  testDomain,
+	}))
+	if handshake != 0.25 {
+		t.Errorf("Expected handshake duration 0.25, got %f", handshake)
+	}
+
 	// Verify log output contains expected information
 	if !bytes.Contains(logBuf.Bytes(), []byte(testLabel)) {
 		t.Error("Log output should contain monitor label")
@@ -260,6 +480,143 @@ func TestTLSMonitor_pushToPrometheus(t *testing.T) {
 	}
 }
 
+func TestTLSMonitor_pushToPrometheus_Chain(t *testing.T) {
+	ca := newTestCertificate(t, &x509.Certificate{IsCA: true, Subject: pkix.Name{CommonName: "Root CA"}}, nil)
+	intermediate := newTestCertificate(t, &x509.Certificate{
+		IsCA:     true,
+		Subject:  pkix.Name{CommonName: "Intermediate CA"},
+		NotAfter: time.Now().Add(time.Hour).Truncate(time.Second),
+	}, ca)
+	leaf := newTestCertificate(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "example.com"},
+		DNSNames:    []string{"example.com", "www.example.com"},
+		IPAddresses: []net.IP{net.ParseIP("192.0.2.1")},
+		NotAfter:    time.Now().Add(48 * time.Hour).Truncate(time.Second),
+	}, intermediate)
+
+	collector := TLSMonitorFactory{}.CreateCollector()
+	monitor := &TLSMonitor{
+		Label:   "site",
+		Domain:  "example.com",
+		Logger:  log.New(bytes.NewBuffer(nil), "", 0),
+		Metrics: collector,
+	}
+
+	monitor.pushToPrometheus(&TLSHealthCheckerData{
+		Expires:      leaf.Cert.NotAfter,
+		Certificates: []*x509.Certificate{leaf.Cert, intermediate.Cert},
+		Version:      tls.VersionTLS13,
+		CipherSuite:  tls.TLS_AES_128_GCM_SHA256,
+	})
+
+	labels := prometheus.Labels{"tls_monitor_name": "site", "tls_domain_name": "example.com"}
+	tests := []struct {
+		cert   *testCertificate
+		issuer string
+		sans   string
+	}{
+		{cert: leaf, issuer: "Intermediate CA", sans: "example.com,www.example.com,192.0.2.1"},
+		{cert: intermediate, issuer: "Root CA", sans: ""},
+	}
+	for _, tt := range tests {
+		values := []string{tt.cert.Cert.SerialNumber.Text(16), tt.issuer, tt.cert.Cert.Subject.CommonName, tt.sans}
+		notAfter := testutil.ToFloat64(collector.CertNotAfter.MustCurryWith(labels).WithLabelValues(values...))
+		if notAfter != float64(tt.cert.Cert.NotAfter.Unix()) {
+			t.Errorf("%s: expected not_after %d, got %f", tt.cert.Cert.Subject.CommonName, tt.cert.Cert.NotAfter.Unix(), notAfter)
+		}
+		notBefore := testutil.ToFloat64(collector.CertNotBefore.MustCurryWith(labels).WithLabelValues(values...))
+		if notBefore != float64(tt.cert.Cert.NotBefore.Unix()) {
+			t.Errorf("%s: expected not_before %d, got %f", tt.cert.Cert.Subject.CommonName, tt.cert.Cert.NotBefore.Unix(), notBefore)
+		}
+	}
+
+	info := testutil.ToFloat64(collector.ConnectionInfo.MustCurryWith(labels).WithLabelValues("TLS 1.3", "TLS_AES_128_GCM_SHA256"))
+	if info != 1 {
+		t.Errorf("Expected connection info 1, got %f", info)
+	}
+
+	// A renewed chain replaces the previous certificates.
+	monitor.pushToPrometheus(&TLSHealthCheckerData{
+		Expires:      leaf.Cert.NotAfter,
+		Certificates: []*x509.Certificate{leaf.Cert},
+		Version:      tls.VersionTLS12,
+		CipherSuite:  tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	})
+	if count := testutil.CollectAndCount(collector, "labtime_tls_cert_not_after"); count != 1 {
+		t.Errorf("Expected 1 certificate series, got %d", count)
+	}
+	if count := testutil.CollectAndCount(collector, "labtime_tls_connection_info"); count != 1 {
+		t.Errorf("Expected 1 connection info series, got %d", count)
+	}
+}
+
+func TestTLSMonitor_pushToPrometheus_Fingerprint(t *testing.T) {
+	first := newTestCertificate(t, &x509.Certificate{}, nil)
+	renewed := newTestCertificate(t, &x509.Certificate{}, nil)
+
+	var logBuf bytes.Buffer
+	collector := TLSMonitorFactory{}.CreateCollector()
+	monitor := &TLSMonitor{
+		Label:   "site",
+		Domain:  "example.com",
+		Logger:  log.New(&logBuf, "", 0),
+		Metrics: collector,
+	}
+	labels := prometheus.Labels{"tls_monitor_name": "site", "tls_domain_name": "example.com"}
+
+	monitor.pushToPrometheus(&TLSHealthCheckerData{Certificates: []*x509.Certificate{first.Cert}})
+	if testutil.ToFloat64(collector.CertChanged.With(labels)) == 0 {
+		t.Error("Expected the change timestamp to be set on the first run")
+	}
+
+	// The timestamp is kept while the certificate does not change.
+	collector.CertChanged.With(labels).Set(1)
+	monitor.pushToPrometheus(&TLSHealthCheckerData{Certificates: []*x509.Certificate{first.Cert}})
+	if changed := testutil.ToFloat64(collector.CertChanged.With(labels)); changed != 1 {
+		t.Errorf("Expected unchanged timestamp, got %f", changed)
+	}
+
+	monitor.pushToPrometheus(&TLSHealthCheckerData{Certificates: []*x509.Certificate{renewed.Cert}})
+	if changed := testutil.ToFloat64(collector.CertChanged.With(labels)); changed == 1 {
+		t.Error("Expected the change timestamp to be updated")
+	}
+	if count := testutil.CollectAndCount(collector, "labtime_tls_cert_fingerprint_info"); count != 1 {
+		t.Errorf("Expected 1 fingerprint series, got %d", count)
+	}
+	info := testutil.ToFloat64(collector.Fingerprint.MustCurryWith(labels).WithLabelValues(certificateFingerprint(renewed.Cert)))
+	if info != 1 {
+		t.Errorf("Expected fingerprint info 1, got %f", info)
+	}
+	if !bytes.Contains(logBuf.Bytes(), []byte("changed")) {
+		t.Error("Log output should contain the certificate change")
+	}
+}
+
+func TestTLSMonitor_pushToPrometheus_Concurrent(t *testing.T) {
+	first := newTestCertificate(t, &x509.Certificate{}, nil)
+	renewed := newTestCertificate(t, &x509.Certificate{}, nil)
+
+	monitor := &TLSMonitor{
+		Label:   "site",
+		Domain:  "example.com",
+		Logger:  log.New(io.Discard, "", 0),
+		Metrics: TLSMonitorFactory{}.CreateCollector(),
+	}
+
+	// Overlapping runs must not race on the previous fingerprint.
+	var wg sync.WaitGroup
+	for _, cert := range []*x509.Certificate{first.Cert, renewed.Cert} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				monitor.pushToPrometheus(&TLSHealthCheckerData{Certificates: []*x509.Certificate{cert}})
+			}
+		}()
+	}
+	wg.Wait()
+}
+
 // Helper function to calculate absolute value of float64.
 func abs(x float64) float64 {
 	if x < 0 {
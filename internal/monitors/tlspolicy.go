@@ -0,0 +1,161 @@
+package monitors
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"slices"
+
+	"aireone.xyz/labtime/internal/yamlconfig"
+	"github.com/pkg/errors"
+)
+
+// Rules reported by the labtime_tls_policy_violation metric.
+const (
+	tlsPolicyRuleKeyType        = "key_type"
+	tlsPolicyRuleKeySize        = "key_size"
+	tlsPolicyRuleSHA1Signature  = "sha1_signature"
+	tlsPolicyRuleTLS10Accepted  = "tls10_accepted"
+	tlsPolicyRuleTLS11Accepted  = "tls11_accepted"
+	tlsPolicyRuleExpectedIssuer = "unexpected_issuer"
+)
+
+// Key types of the certificates.
+const (
+	keyTypeRSA     = "rsa"
+	keyTypeECDSA   = "ecdsa"
+	keyTypeEd25519 = "ed25519"
+)
+
+// Default minimum key sizes of the TLS policy.
+const (
+	defaultMinRSAKeySize   = 2048
+	defaultMinECDSAKeySize = 256
+)
+
+// TLSPolicy holds the rules evaluated against the TLS services.
+type TLSPolicy struct {
+	AllowedKeyTypes     []string
+	MinRSAKeySize       int
+	MinECDSAKeySize     int
+	AllowSHA1           bool
+	ProbeLegacyVersions bool
+	// ExpectedIssuer matches the common name or the organization of the leaf
+	// certificate issuer. Empty disables the rule.
+	ExpectedIssuer string
+}
+
+// newTLSPolicy validates the policy settings of a target. It returns nil
+// when no policy is configured.
+func newTLSPolicy(dto *yamlconfig.TLSPolicyDTO) (*TLSPolicy, error) {
+	if dto == nil {
+		return nil, nil //nolint:nilnil // no policy is not an error
+	}
+
+	policy := &TLSPolicy{
+		AllowedKeyTypes:     dto.AllowedKeyTypes,
+		MinRSAKeySize:       dto.MinRSAKeySize,
+		MinECDSAKeySize:     dto.MinECDSAKeySize,
+		AllowSHA1:           dto.AllowSHA1,
+		ProbeLegacyVersions: true,
+		ExpectedIssuer:      dto.ExpectedIssuer,
+	}
+
+	if len(policy.AllowedKeyTypes) == 0 {
+		policy.AllowedKeyTypes = []string{keyTypeRSA, keyTypeECDSA, keyTypeEd25519}
+	}
+	for _, keyType := range policy.AllowedKeyTypes {
+		if keyType != keyTypeRSA && keyType != keyTypeECDSA && keyType != keyTypeEd25519 {
+			return nil, errors.Errorf("invalid key type %q", keyType)
+		}
+	}
+	if policy.MinRSAKeySize == 0 {
+		policy.MinRSAKeySize = defaultMinRSAKeySize
+	} else if policy.MinRSAKeySize < 0 {
+		return nil, errors.Errorf("invalid min_rsa_key_size %d", policy.MinRSAKeySize)
+	}
+	if policy.MinECDSAKeySize == 0 {
+		policy.MinECDSAKeySize = defaultMinECDSAKeySize
+	} else if policy.MinECDSAKeySize < 0 {
+		return nil, errors.Errorf("invalid min_ecdsa_key_size %d", policy.MinECDSAKeySize)
+	}
+	if dto.ProbeLegacyVersions != nil {
+		policy.ProbeLegacyVersions = *dto.ProbeLegacyVersions
+	}
+
+	return policy, nil
+}
+
+// evaluateCertificates evaluates the certificate rules against the chain
+// presented by the server, leaf first. It returns whether each rule is
+// violated.
+func (p *TLSPolicy) evaluateCertificates(certs []*x509.Certificate) map[string]bool {
+	violations := map[string]bool{}
+	if len(certs) == 0 {
+		return violations
+	}
+	leaf := certs[0]
+
+	keyType, keySize := publicKeyTypeAndSize(leaf.PublicKey)
+	violations[tlsPolicyRuleKeyType] = !slices.Contains(p.AllowedKeyTypes, keyType)
+	switch keyType {
+	case keyTypeRSA:
+		violations[tlsPolicyRuleKeySize] = keySize < p.MinRSAKeySize
+	case keyTypeECDSA:
+		violations[tlsPolicyRuleKeySize] = keySize < p.MinECDSAKeySize
+	default:
+		violations[tlsPolicyRuleKeySize] = false
+	}
+
+	if !p.AllowSHA1 {
+		violations[tlsPolicyRuleSHA1Signature] = slices.ContainsFunc(certs, func(cert *x509.Certificate) bool {
+			// The signature of a self-signed root is not verified.
+			selfSigned := bytes.Equal(cert.RawIssuer, cert.RawSubject)
+			return !selfSigned && isSHA1Signature(cert.SignatureAlgorithm)
+		})
+	}
+
+	if p.ExpectedIssuer != "" {
+		violations[tlsPolicyRuleExpectedIssuer] = leaf.Issuer.CommonName != p.ExpectedIssuer &&
+			!slices.Contains(leaf.Issuer.Organization, p.ExpectedIssuer)
+	}
+
+	return violations
+}
+
+// legacyTLSVersions are the deprecated TLS versions probed by the policy,
+// with their rule.
+var legacyTLSVersions = []struct {
+	Version uint16
+	Rule    string
+}{
+	{Version: tls.VersionTLS10, Rule: tlsPolicyRuleTLS10Accepted},
+	{Version: tls.VersionTLS11, Rule: tlsPolicyRuleTLS11Accepted},
+}
+
+// publicKeyTypeAndSize returns the type and the size (in bits) of a public
+// key.
+func publicKeyTypeAndSize(pub any) (string, int) {
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+		return keyTypeRSA, key.N.BitLen()
+	case *ecdsa.PublicKey:
+		return keyTypeECDSA, key.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		return keyTypeEd25519, 256
+	default:
+		return "unknown", 0
+	}
+}
+
+func isSHA1Signature(algorithm x509.SignatureAlgorithm) bool {
+	switch algorithm {
+	case x509.SHA1WithRSA, x509.DSAWithSHA1, x509.ECDSAWithSHA1:
+		return true
+	default:
+		return false
+	}
+}
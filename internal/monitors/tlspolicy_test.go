@@ -0,0 +1,173 @@
+package monitors
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"log"
+	"net"
+	"strconv"
+	"testing"
+
+	"aireone.xyz/labtime/internal/yamlconfig"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestNewTLSPolicy(t *testing.T) {
+	policy, err := newTLSPolicy(nil)
+	if err != nil || policy != nil {
+		t.Errorf("Expected no policy, got %+v (%v)", policy, err)
+	}
+
+	policy, err = newTLSPolicy(&yamlconfig.TLSPolicyDTO{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if policy.MinRSAKeySize != 2048 || policy.MinECDSAKeySize != 256 || len(policy.AllowedKeyTypes) != 3 || !policy.ProbeLegacyVersions {
+		t.Errorf("Unexpected default policy: %+v", policy)
+	}
+
+	noProbe := false
+	policy, err = newTLSPolicy(&yamlconfig.TLSPolicyDTO{ProbeLegacyVersions: &noProbe, AllowedKeyTypes: []string{"ecdsa"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if policy.ProbeLegacyVersions || len(policy.AllowedKeyTypes) != 1 {
+		t.Errorf("Unexpected explicit policy: %+v", policy)
+	}
+
+	if _, err := newTLSPolicy(&yamlconfig.TLSPolicyDTO{AllowedKeyTypes: []string{"dsa"}}); err == nil {
+		t.Error("Expected error for invalid key type but got none")
+	}
+	if _, err := newTLSPolicy(&yamlconfig.TLSPolicyDTO{MinRSAKeySize: -1}); err == nil {
+		t.Error("Expected error for invalid key size but got none")
+	}
+}
+
+func TestTLSPolicy_evaluateCertificates(t *testing.T) {
+	ca := newTestCertificate(t, &x509.Certificate{IsCA: true, Subject: pkix.Name{CommonName: "Lab CA", Organization: []string{"Lab"}}}, nil)
+	leaf := newTestCertificate(t, &x509.Certificate{}, ca)
+
+	// SHA-1 signatures can't be created anymore, the certificate fields are
+	// set directly.
+	sha1Leaf := *leaf.Cert
+	sha1Leaf.SignatureAlgorithm = x509.ECDSAWithSHA1
+	sha1Root := *ca.Cert
+	sha1Root.SignatureAlgorithm = x509.ECDSAWithSHA1
+
+	tests := []struct {
+		name     string
+		policy   TLSPolicy
+		chain    []*x509.Certificate
+		expected map[string]bool
+	}{
+		{
+			name:   "compliant",
+			policy: TLSPolicy{AllowedKeyTypes: []string{"ecdsa"}, MinECDSAKeySize: 256, ExpectedIssuer: "Lab CA"},
+			chain:  []*x509.Certificate{leaf.Cert, ca.Cert},
+			expected: map[string]bool{
+				tlsPolicyRuleKeyType:        false,
+				tlsPolicyRuleKeySize:        false,
+				tlsPolicyRuleSHA1Signature:  false,
+				tlsPolicyRuleExpectedIssuer: false,
+			},
+		},
+		{
+			name:   "violations",
+			policy: TLSPolicy{AllowedKeyTypes: []string{"rsa"}, MinECDSAKeySize: 384, ExpectedIssuer: "Let's Encrypt"},
+			chain:  []*x509.Certificate{&sha1Leaf, ca.Cert},
+			expected: map[string]bool{
+				tlsPolicyRuleKeyType:        true,
+				tlsPolicyRuleKeySize:        true,
+				tlsPolicyRuleSHA1Signature:  true,
+				tlsPolicyRuleExpectedIssuer: true,
+			},
+		},
+		{
+			name:   "issuer organization and self-signed SHA-1 root",
+			policy: TLSPolicy{AllowedKeyTypes: []string{"ecdsa"}, MinECDSAKeySize: 256, ExpectedIssuer: "Lab"},
+			chain:  []*x509.Certificate{leaf.Cert, &sha1Root},
+			expected: map[string]bool{
+				tlsPolicyRuleKeyType:        false,
+				tlsPolicyRuleKeySize:        false,
+				tlsPolicyRuleSHA1Signature:  false,
+				tlsPolicyRuleExpectedIssuer: false,
+			},
+		},
+		{
+			name:   "SHA-1 allowed",
+			policy: TLSPolicy{AllowedKeyTypes: []string{"ecdsa"}, MinECDSAKeySize: 256, AllowSHA1: true},
+			chain:  []*x509.Certificate{&sha1Leaf},
+			expected: map[string]bool{
+				tlsPolicyRuleKeyType: false,
+				tlsPolicyRuleKeySize: false,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.policy.evaluateCertificates(tt.chain)
+			if len(got) != len(tt.expected) {
+				t.Errorf("Expected rules %v, got %v", tt.expected, got)
+			}
+			for rule, violated := range tt.expected {
+				if got[rule] != violated {
+					t.Errorf("Rule %s: expected %v, got %v", rule, violated, got[rule])
+				}
+			}
+		})
+	}
+}
+
+func TestTLSMonitor_Run_PolicyLegacyVersions(t *testing.T) {
+	leaf := newTestCertificate(t, &x509.Certificate{DNSNames: []string{"example.com"}}, nil)
+
+	tests := []struct {
+		name          string
+		minVersion    uint16
+		expectedValue float64
+	}{
+		{name: "legacy versions accepted", minVersion: tls.VersionTLS10, expectedValue: 1},
+		{name: "legacy versions refused", minVersion: tls.VersionTLS12, expectedValue: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr := newTestTLSListenerWithConfig(t, &tls.Config{
+				Certificates: []tls.Certificate{leaf.TLSCertificate()},
+				MinVersion:   tt.minVersion,
+			})
+			host, port, _ := net.SplitHostPort(addr)
+			portNumber, _ := strconv.Atoi(port)
+
+			policy, err := newTLSPolicy(&yamlconfig.TLSPolicyDTO{})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			collector := TLSMonitorFactory{}.CreateCollector()
+			monitor := TLSMonitorFactory{}.CreateMonitor(TLSTarget{
+				Name:    "legacy",
+				Domain:  "example.com",
+				Port:    portNumber,
+				Address: host,
+				Policy:  policy,
+			}, collector, log.New(bytes.NewBuffer(nil), "", 0))
+
+			if err := monitor.Run(t.Context()); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			labels := prometheus.Labels{"tls_monitor_name": "legacy", "tls_domain_name": "example.com"}
+			for _, rule := range []string{tlsPolicyRuleTLS10Accepted, tlsPolicyRuleTLS11Accepted} {
+				got := testutil.ToFloat64(collector.Violation.MustCurryWith(labels).WithLabelValues(rule))
+				if got != tt.expectedValue {
+					t.Errorf("Rule %s: expected %f, got %f", rule, tt.expectedValue, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package monitors
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ocsp"
+)
+
+// Revocation check methods, used as the method label of the
+// labtime_tls_cert_revoked metric.
+const (
+	revocationMethodOCSP = "ocsp"
+	revocationMethodCRL  = "crl"
+)
+
+// revocationMaxSize is the maximum size of the OCSP responses and CRLs.
+const revocationMaxSize = 10 << 20
+
+// defaultRevocationTimeout is the timeout of the OCSP and CRL requests.
+const defaultRevocationTimeout = 10 * time.Second
+
+// revocationClockSkew is the clock difference with the OCSP responders and
+// the CRL issuers tolerated by the freshness checks.
+const revocationClockSkew = 5 * time.Minute
+
+// RevocationResult holds the outcome of a revocation check.
+type RevocationResult struct {
+	Method  string
+	Revoked bool
+	Err     error
+}
+
+// checkOCSP checks the revocation status of the leaf certificate with the
+// stapled OCSP response, or with its OCSP responder when none is stapled.
+func checkOCSP(ctx context.Context, client HTTPClient, certs []*x509.Certificate, staple []byte) RevocationResult {
+	result := RevocationResult{Method: revocationMethodOCSP}
+
+	if len(certs) < 2 {
+		result.Err = errors.New("the issuer certificate is not part of the chain")
+		return result
+	}
+	leaf, issuer := certs[0], certs[1]
+
+	if len(staple) == 0 {
+		var err error
+		staple, err = queryOCSPResponder(ctx, client, leaf, issuer)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+	}
+
+	resp, err := ocsp.ParseResponseForCert(staple, leaf, issuer)
+	if err != nil {
+		result.Err = errors.Wrap(err, "invalid OCSP response")
+		return result
+	}
+	if err := checkRevocationFreshness(resp.ThisUpdate, resp.NextUpdate, time.Now()); err != nil {
+		result.Err = errors.Wrap(err, "stale OCSP response")
+		return result
+	}
+
+	switch resp.Status {
+	case ocsp.Good:
+	case ocsp.Revoked:
+		result.Revoked = true
+	default:
+		result.Err = errors.New("unknown OCSP status")
+	}
+	return result
+}
+
+// queryOCSPResponder fetches the OCSP response of the certificate from the
+// responder listed in the certificate.
+func queryOCSPResponder(ctx context.Context, client HTTPClient, leaf, issuer *x509.Certificate) ([]byte, error) {
+	if len(leaf.OCSPServer) == 0 {
+		return nil, errors.New("no OCSP staple and no OCSP responder in the certificate")
+	}
+
+	request, err := ocsp.CreateRequest(leaf, issuer, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating OCSP request")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, leaf.OCSPServer[0], bytes.NewReader(request))
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating OCSP http request")
+	}
+	req.Header.Set("Content-Type", "application/ocsp-request")
+	req.Header.Set("Accept", "application/ocsp-response")
+
+	return fetchRevocationData(client, req)
+}
+
+// checkCRL checks the revocation status of the leaf certificate with the CRL
+// listed in the certificate.
+func checkCRL(ctx context.Context, client HTTPClient, certs []*x509.Certificate) RevocationResult {
+	result := RevocationResult{Method: revocationMethodCRL}
+
+	if len(certs) < 2 {
+		result.Err = errors.New("the issuer certificate is not part of the chain")
+		return result
+	}
+	leaf, issuer := certs[0], certs[1]
+
+	if len(leaf.CRLDistributionPoints) == 0 {
+		result.Err = errors.New("no CRL distribution point in the certificate")
+		return result
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, leaf.CRLDistributionPoints[0], http.NoBody)
+	if err != nil {
+		result.Err = errors.Wrap(err, "error creating CRL http request")
+		return result
+	}
+	der, err := fetchRevocationData(client, req)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	crl, err := x509.ParseRevocationList(der)
+	if err != nil {
+		result.Err = errors.Wrap(err, "invalid CRL")
+		return result
+	}
+	if err := crl.CheckSignatureFrom(issuer); err != nil {
+		result.Err = errors.Wrap(err, "invalid CRL signature")
+		return result
+	}
+	if err := checkRevocationFreshness(crl.ThisUpdate, crl.NextUpdate, time.Now()); err != nil {
+		result.Err = errors.Wrap(err, "stale CRL")
+		return result
+	}
+
+	for _, entry := range crl.RevokedCertificateEntries {
+		if entry.SerialNumber.Cmp(leaf.SerialNumber) == 0 {
+			result.Revoked = true
+			break
+		}
+	}
+	return result
+}
+
+// checkRevocationFreshness checks that an OCSP response or a CRL is valid at
+// now. A zero nextUpdate means that no expiry is set.
+func checkRevocationFreshness(thisUpdate, nextUpdate, now time.Time) error {
+	if thisUpdate.After(now.Add(revocationClockSkew)) {
+		return errors.Errorf("not valid before %s", thisUpdate.Format(time.RFC3339))
+	}
+	if !nextUpdate.IsZero() && nextUpdate.Before(now.Add(-revocationClockSkew)) {
+		return errors.Errorf("expired since %s", nextUpdate.Format(time.RFC3339))
+	}
+	return nil
+}
+
+func fetchRevocationData(client HTTPClient, req *http.Request) ([]byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error requesting %s", req.URL)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("unexpected status code %d from %s", resp.StatusCode, req.URL)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, revocationMaxSize))
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading response from %s", req.URL)
+	}
+	return data, nil
+}
@@ -0,0 +1,272 @@
+package monitors
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/x509"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"golang.org/x/crypto/ocsp"
+)
+
+// newTestOCSPResponse creates an OCSP response for the certificate signed by
+// the issuer.
+func newTestOCSPResponse(t *testing.T, cert *x509.Certificate, issuer *testCertificate, status int) []byte {
+	t.Helper()
+	return newTestOCSPResponseAt(t, cert, issuer, status, time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+}
+
+// newTestOCSPResponseAt creates an OCSP response valid between thisUpdate and
+// nextUpdate.
+func newTestOCSPResponseAt(t *testing.T, cert *x509.Certificate, issuer *testCertificate, status int, thisUpdate, nextUpdate time.Time) []byte {
+	t.Helper()
+
+	template := ocsp.Response{
+		Status:       status,
+		SerialNumber: cert.SerialNumber,
+		ThisUpdate:   thisUpdate,
+		NextUpdate:   nextUpdate,
+	}
+	if status == ocsp.Revoked {
+		template.RevokedAt = time.Now().Add(-time.Minute)
+	}
+	resp, err := ocsp.CreateResponse(issuer.Cert, issuer.Cert, template, issuer.Key)
+	if err != nil {
+		t.Fatalf("Failed to create OCSP response: %v", err)
+	}
+	return resp
+}
+
+// newTestOCSPResponder starts an OCSP responder answering with the status.
+func newTestOCSPResponder(t *testing.T, issuer *testCertificate, status int) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		req, err := ocsp.ParseRequest(body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		cert := &x509.Certificate{SerialNumber: req.SerialNumber}
+		_, _ = w.Write(newTestOCSPResponse(t, cert, issuer, status))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCheckOCSP(t *testing.T) {
+	ca := newTestCertificate(t, &x509.Certificate{IsCA: true}, nil)
+
+	tests := []struct {
+		name            string
+		status          int
+		stapled         bool
+		expectedRevoked bool
+		expectError     bool
+	}{
+		{name: "stapled good", status: ocsp.Good, stapled: true},
+		{name: "stapled revoked", status: ocsp.Revoked, stapled: true, expectedRevoked: true},
+		{name: "responder good", status: ocsp.Good},
+		{name: "responder revoked", status: ocsp.Revoked, expectedRevoked: true},
+		{name: "responder unknown", status: ocsp.Unknown, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			responder := newTestOCSPResponder(t, ca, tt.status)
+			leaf := newTestCertificate(t, &x509.Certificate{OCSPServer: []string{responder.URL}}, ca)
+
+			var staple []byte
+			if tt.stapled {
+				staple = newTestOCSPResponse(t, leaf.Cert, ca, tt.status)
+				responder.Close()
+			}
+
+			result := checkOCSP(t.Context(), &http.Client{}, []*x509.Certificate{leaf.Cert, ca.Cert}, staple)
+			if tt.expectError {
+				if result.Err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if result.Err != nil {
+				t.Fatalf("Unexpected error: %v", result.Err)
+			}
+			if result.Revoked != tt.expectedRevoked {
+				t.Errorf("Expected revoked %v, got %v", tt.expectedRevoked, result.Revoked)
+			}
+		})
+	}
+
+	t.Run("stale staple", func(t *testing.T) {
+		leaf := newTestCertificate(t, &x509.Certificate{}, ca)
+		now := time.Now()
+		staples := map[string][]byte{
+			"expired":       newTestOCSPResponseAt(t, leaf.Cert, ca, ocsp.Good, now.Add(-48*time.Hour), now.Add(-24*time.Hour)),
+			"not yet valid": newTestOCSPResponseAt(t, leaf.Cert, ca, ocsp.Good, now.Add(24*time.Hour), now.Add(48*time.Hour)),
+		}
+		for name, staple := range staples {
+			if result := checkOCSP(t.Context(), &http.Client{}, []*x509.Certificate{leaf.Cert, ca.Cert}, staple); result.Err == nil {
+				t.Errorf("%s: expected error but got none", name)
+			}
+		}
+	})
+
+	t.Run("no issuer", func(t *testing.T) {
+		leaf := newTestCertificate(t, &x509.Certificate{}, ca)
+		if result := checkOCSP(t.Context(), &http.Client{}, []*x509.Certificate{leaf.Cert}, nil); result.Err == nil {
+			t.Error("Expected error but got none")
+		}
+	})
+
+	t.Run("no responder", func(t *testing.T) {
+		leaf := newTestCertificate(t, &x509.Certificate{}, ca)
+		if result := checkOCSP(t.Context(), &http.Client{}, []*x509.Certificate{leaf.Cert, ca.Cert}, nil); result.Err == nil {
+			t.Error("Expected error but got none")
+		}
+	})
+}
+
+func TestCheckCRL(t *testing.T) {
+	ca := newTestCertificate(t, &x509.Certificate{IsCA: true, KeyUsage: x509.KeyUsageCRLSign}, nil)
+	other := newTestCertificate(t, &x509.Certificate{IsCA: true, KeyUsage: x509.KeyUsageCRLSign}, nil)
+	revokedSerial := big.NewInt(42)
+
+	newCRL := func(issuer *testCertificate, nextUpdate time.Time) []byte {
+		crl, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+			Number:     big.NewInt(1),
+			ThisUpdate: nextUpdate.Add(-2 * time.Hour),
+			NextUpdate: nextUpdate,
+			RevokedCertificateEntries: []x509.RevocationListEntry{
+				{SerialNumber: revokedSerial, RevocationTime: time.Now().Add(-time.Minute)},
+			},
+		}, issuer.Cert, issuer.Key)
+		if err != nil {
+			t.Fatalf("Failed to create CRL: %v", err)
+		}
+		return crl
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ca.crl", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(newCRL(ca, time.Now().Add(time.Hour)))
+	})
+	mux.HandleFunc("/other.crl", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(newCRL(other, time.Now().Add(time.Hour)))
+	})
+	mux.HandleFunc("/expired.crl", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(newCRL(ca, time.Now().Add(-time.Hour)))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		name            string
+		serial          *big.Int
+		crlPath         string
+		expectedRevoked bool
+		expectError     bool
+	}{
+		{name: "not revoked", serial: big.NewInt(7), crlPath: "/ca.crl"},
+		{name: "revoked", serial: revokedSerial, crlPath: "/ca.crl", expectedRevoked: true},
+		{name: "invalid signature", serial: revokedSerial, crlPath: "/other.crl", expectError: true},
+		{name: "not found", serial: revokedSerial, crlPath: "/missing.crl", expectError: true},
+		{name: "expired", serial: big.NewInt(7), crlPath: "/expired.crl", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			leaf := newTestCertificate(t, &x509.Certificate{
+				SerialNumber:          tt.serial,
+				CRLDistributionPoints: []string{server.URL + tt.crlPath},
+			}, ca)
+
+			result := checkCRL(t.Context(), &http.Client{}, []*x509.Certificate{leaf.Cert, ca.Cert})
+			if tt.expectError {
+				if result.Err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if result.Err != nil {
+				t.Fatalf("Unexpected error: %v", result.Err)
+			}
+			if result.Revoked != tt.expectedRevoked {
+				t.Errorf("Expected revoked %v, got %v", tt.expectedRevoked, result.Revoked)
+			}
+		})
+	}
+}
+
+func TestTLSMonitor_Run_OCSPStaple(t *testing.T) {
+	ca := newTestCertificate(t, &x509.Certificate{IsCA: true}, nil)
+	leaf := newTestCertificate(t, &x509.Certificate{DNSNames: []string{"example.com"}}, ca)
+	certificate := leaf.TLSCertificate(ca)
+	certificate.OCSPStaple = newTestOCSPResponse(t, leaf.Cert, ca, ocsp.Revoked)
+
+	addr := newTestTLSListener(t, certificate)
+	host, port, _ := net.SplitHostPort(addr)
+	portNumber, _ := strconv.Atoi(port)
+
+	collector := TLSMonitorFactory{}.CreateCollector()
+	monitor := TLSMonitorFactory{}.CreateMonitor(TLSTarget{
+		Name:    "revoked",
+		Domain:  "example.com",
+		Port:    portNumber,
+		Address: host,
+		OCSP:    true,
+	}, collector, log.New(bytes.NewBuffer(nil), "", 0))
+
+	if err := monitor.Run(t.Context()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	labels := prometheus.Labels{"tls_monitor_name": "revoked", "tls_domain_name": "example.com"}
+	if got := testutil.ToFloat64(collector.OCSPStapled.With(labels)); got != 1 {
+		t.Errorf("Expected OCSP staple present, got %f", got)
+	}
+	if got := testutil.ToFloat64(collector.Revoked.MustCurryWith(labels).WithLabelValues(revocationMethodOCSP)); got != 1 {
+		t.Errorf("Expected revoked certificate, got %f", got)
+	}
+}
+
+func TestTLSMonitor_Run_ExpiredOCSPStaple(t *testing.T) {
+	ca := newTestCertificate(t, &x509.Certificate{IsCA: true}, nil)
+	leaf := newTestCertificate(t, &x509.Certificate{DNSNames: []string{"example.com"}}, ca)
+	certificate := leaf.TLSCertificate(ca)
+	certificate.OCSPStaple = newTestOCSPResponseAt(t, leaf.Cert, ca, ocsp.Good,
+		time.Now().Add(-48*time.Hour), time.Now().Add(-24*time.Hour))
+
+	addr := newTestTLSListener(t, certificate)
+	host, port, _ := net.SplitHostPort(addr)
+	portNumber, _ := strconv.Atoi(port)
+
+	collector := TLSMonitorFactory{}.CreateCollector()
+	monitor := TLSMonitorFactory{}.CreateMonitor(TLSTarget{
+		Name:    "expired-staple",
+		Domain:  "example.com",
+		Port:    portNumber,
+		Address: host,
+		OCSP:    true,
+	}, collector, log.New(bytes.NewBuffer(nil), "", 0))
+
+	if err := monitor.Run(t.Context()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The stale response is a failed check, not a good status.
+	if count := testutil.CollectAndCount(collector.Revoked); count != 0 {
+		t.Errorf("Expected no revocation series, got %d", count)
+	}
+}
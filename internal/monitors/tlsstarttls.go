@@ -0,0 +1,340 @@
+package monitors
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// STARTTLS protocols supported by the TLS monitors.
+const (
+	startTLSSMTP     = "smtp"
+	startTLSIMAP     = "imap"
+	startTLSPOP3     = "pop3"
+	startTLSLDAP     = "ldap"
+	startTLSXMPP     = "xmpp"
+	startTLSFTP      = "ftp"
+	startTLSPostgres = "postgres"
+)
+
+var ErrStartTLS = errors.New("starttls negotiation failed")
+
+// startTLSDefaultPorts are the default ports of the STARTTLS protocols.
+var startTLSDefaultPorts = map[string]int{
+	startTLSSMTP:     25,
+	startTLSIMAP:     143,
+	startTLSPOP3:     110,
+	startTLSLDAP:     389,
+	startTLSXMPP:     5222,
+	startTLSFTP:      21,
+	startTLSPostgres: 5432,
+}
+
+func isValidStartTLS(protocol string) bool {
+	_, ok := startTLSDefaultPorts[protocol]
+	return ok
+}
+
+// startTLS upgrades the plain text connection so the TLS handshake can start.
+// The domain is announced to the server by the protocols requiring it.
+func startTLS(conn net.Conn, protocol, domain string) error {
+	r := bufio.NewReader(conn)
+
+	var err error
+	switch protocol {
+	case startTLSSMTP:
+		err = startTLSWithSMTP(conn, r)
+	case startTLSIMAP:
+		err = startTLSWithIMAP(conn, r)
+	case startTLSPOP3:
+		err = startTLSWithPOP3(conn, r)
+	case startTLSLDAP:
+		err = startTLSWithLDAP(conn, r)
+	case startTLSXMPP:
+		err = startTLSWithXMPP(conn, r, domain)
+	case startTLSFTP:
+		err = startTLSWithFTP(conn, r)
+	case startTLSPostgres:
+		err = startTLSWithPostgres(conn, r)
+	default:
+		return errors.Errorf("unsupported starttls protocol %q", protocol)
+	}
+	if err != nil {
+		return errors.Wrapf(ErrStartTLS, "%s: %v", protocol, err)
+	}
+
+	// The server must not send anything before the client hello.
+	if r.Buffered() > 0 {
+		return errors.Wrapf(ErrStartTLS, "%s: unexpected data after the starttls response", protocol)
+	}
+	return nil
+}
+
+// readReplyCode reads a (possibly multiline) SMTP or FTP reply and returns
+// its status code.
+func readReplyCode(r *bufio.Reader) (string, error) {
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			return "", err
+		}
+		if len(line) < 3 {
+			return "", errors.Errorf("invalid reply %q", line)
+		}
+		// Multiline replies use a dash after the code, except on the last
+		// line.
+		if len(line) == 3 || line[3] != '-' {
+			return line[:3], nil
+		}
+	}
+}
+
+// expectReplyCode reads a reply and checks its status code.
+func expectReplyCode(r *bufio.Reader, expected string) error {
+	code, err := readReplyCode(r)
+	if err != nil {
+		return err
+	}
+	if code != expected {
+		return errors.Errorf("unexpected reply code %s, expected %s", code, expected)
+	}
+	return nil
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", errors.Wrap(err, "error reading server reply")
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func writeLine(w io.Writer, line string) error {
+	_, err := io.WriteString(w, line+"\r\n")
+	return errors.Wrap(err, "error writing command")
+}
+
+func startTLSWithSMTP(conn net.Conn, r *bufio.Reader) error {
+	if err := expectReplyCode(r, "220"); err != nil {
+		return err
+	}
+	if err := writeLine(conn, "EHLO labtime"); err != nil {
+		return err
+	}
+	if err := expectReplyCode(r, "250"); err != nil {
+		return err
+	}
+	if err := writeLine(conn, "STARTTLS"); err != nil {
+		return err
+	}
+	return expectReplyCode(r, "220")
+}
+
+func startTLSWithFTP(conn net.Conn, r *bufio.Reader) error {
+	if err := expectReplyCode(r, "220"); err != nil {
+		return err
+	}
+	if err := writeLine(conn, "AUTH TLS"); err != nil {
+		return err
+	}
+	return expectReplyCode(r, "234")
+}
+
+func startTLSWithIMAP(conn net.Conn, r *bufio.Reader) error {
+	greeting, err := readLine(r)
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(greeting, "* OK") {
+		return errors.Errorf("unexpected greeting %q", greeting)
+	}
+
+	const tag = "labtime1"
+	if err := writeLine(conn, tag+" STARTTLS"); err != nil {
+		return err
+	}
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			return err
+		}
+		// Untagged responses (e.g. capabilities) can precede the result.
+		if status, ok := strings.CutPrefix(line, tag+" "); ok {
+			if !strings.HasPrefix(status, "OK") {
+				return errors.Errorf("unexpected response %q", line)
+			}
+			return nil
+		}
+	}
+}
+
+func startTLSWithPOP3(conn net.Conn, r *bufio.Reader) error {
+	greeting, err := readLine(r)
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(greeting, "+OK") {
+		return errors.Errorf("unexpected greeting %q", greeting)
+	}
+	if err := writeLine(conn, "STLS"); err != nil {
+		return err
+	}
+	line, err := readLine(r)
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "+OK") {
+		return errors.Errorf("unexpected response %q", line)
+	}
+	return nil
+}
+
+func startTLSWithXMPP(conn net.Conn, r *bufio.Reader, domain string) error {
+	header := fmt.Sprintf("<?xml version='1.0'?><stream:stream to='%s' xmlns='jabber:client' "+
+		"xmlns:stream='http://etherx.jabber.org/streams' version='1.0'>", domain)
+	if _, err := io.WriteString(conn, header); err != nil {
+		return errors.Wrap(err, "error writing stream header")
+	}
+	if _, err := readUntil(r, "</stream:features>"); err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(conn, "<starttls xmlns='urn:ietf:params:xml:ns:xmpp-tls'/>"); err != nil {
+		return errors.Wrap(err, "error writing starttls request")
+	}
+	response, err := readUntil(r, ">")
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(response, "<proceed") {
+		return errors.Errorf("unexpected response %q", response)
+	}
+	return nil
+}
+
+// readUntil reads the stream until the delimiter and returns the data read.
+func readUntil(r *bufio.Reader, delimiter string) (string, error) {
+	var buf bytes.Buffer
+	for !bytes.HasSuffix(buf.Bytes(), []byte(delimiter)) {
+		b, err := r.ReadByte()
+		if err != nil {
+			return "", errors.Wrap(err, "error reading server response")
+		}
+		buf.WriteByte(b)
+		if buf.Len() > 64*1024 {
+			return "", errors.New("server response too large")
+		}
+	}
+	return buf.String(), nil
+}
+
+// ldapStartTLSRequest is the LDAP extended request of the StartTLS operation
+// (OID 1.3.6.1.4.1.1466.20037) with the message ID 1.
+var ldapStartTLSRequest = []byte{
+	0x30, 0x1d, // LDAPMessage SEQUENCE
+	0x02, 0x01, 0x01, // messageID INTEGER 1
+	0x77, 0x18, // [APPLICATION 23] ExtendedRequest
+	0x80, 0x16, // [0] requestName
+	'1', '.', '3', '.', '6', '.', '1', '.', '4', '.', '1', '.',
+	'1', '4', '6', '6', '.', '2', '0', '0', '3', '7',
+}
+
+func startTLSWithLDAP(conn net.Conn, r *bufio.Reader) error {
+	if _, err := conn.Write(ldapStartTLSRequest); err != nil {
+		return errors.Wrap(err, "error writing extended request")
+	}
+
+	tag, message, err := readBER(r)
+	if err != nil {
+		return err
+	}
+	if tag != 0x30 {
+		return errors.Errorf("unexpected LDAP message tag 0x%02x", tag)
+	}
+
+	msg := bufio.NewReader(bytes.NewReader(message))
+	if _, _, err := readBER(msg); err != nil { // messageID
+		return err
+	}
+	tag, response, err := readBER(msg)
+	if err != nil {
+		return err
+	}
+	if tag != 0x78 { // [APPLICATION 24] ExtendedResponse
+		return errors.Errorf("unexpected LDAP operation tag 0x%02x", tag)
+	}
+
+	tag, resultCode, err := readBER(bufio.NewReader(bytes.NewReader(response)))
+	if err != nil {
+		return err
+	}
+	if tag != 0x0a || len(resultCode) != 1 {
+		return errors.New("invalid LDAP result code")
+	}
+	if resultCode[0] != 0 {
+		return errors.Errorf("LDAP result code %d", resultCode[0])
+	}
+	return nil
+}
+
+// readBER reads a BER encoded TLV with a single byte tag and returns its tag
+// and value.
+func readBER(r *bufio.Reader) (byte, []byte, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "error reading BER tag")
+	}
+	lengthByte, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "error reading BER length")
+	}
+
+	length := int(lengthByte)
+	if lengthByte&0x80 != 0 {
+		size := int(lengthByte & 0x7f)
+		if size == 0 || size > 3 {
+			return 0, nil, errors.Errorf("unsupported BER length of %d bytes", size)
+		}
+		length = 0
+		for range size {
+			b, err := r.ReadByte()
+			if err != nil {
+				return 0, nil, errors.Wrap(err, "error reading BER length")
+			}
+			length = length<<8 | int(b)
+		}
+	}
+
+	value := make([]byte, length)
+	if _, err := io.ReadFull(r, value); err != nil {
+		return 0, nil, errors.Wrap(err, "error reading BER value")
+	}
+	return tag, value, nil
+}
+
+// postgresSSLRequestCode is the code of the PostgreSQL SSLRequest message.
+const postgresSSLRequestCode = 80877103
+
+func startTLSWithPostgres(conn net.Conn, r *bufio.Reader) error {
+	request := make([]byte, 8)
+	binary.BigEndian.PutUint32(request[0:4], 8)
+	binary.BigEndian.PutUint32(request[4:8], postgresSSLRequestCode)
+	if _, err := conn.Write(request); err != nil {
+		return errors.Wrap(err, "error writing SSLRequest")
+	}
+
+	response, err := r.ReadByte()
+	if err != nil {
+		return errors.Wrap(err, "error reading SSLRequest response")
+	}
+	if response != 'S' {
+		return errors.Errorf("server refused SSL (%q)", response)
+	}
+	return nil
+}
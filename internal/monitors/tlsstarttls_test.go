@@ -0,0 +1,272 @@
+package monitors
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"aireone.xyz/labtime/internal/yamlconfig"
+)
+
+// fakeStartTLSServer plays the server side of a STARTTLS negotiation.
+type fakeStartTLSServer func(conn net.Conn, r *bufio.Reader) error
+
+// expectLine reads a line and checks it.
+func expectLine(r *bufio.Reader, expected string) error {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if strings.TrimRight(line, "\r\n") != expected {
+		return errors.New("unexpected line " + line)
+	}
+	return nil
+}
+
+func writeAll(conn net.Conn, data string) error {
+	_, err := io.WriteString(conn, data)
+	return err
+}
+
+var fakeStartTLSServers = map[string]fakeStartTLSServer{
+	startTLSSMTP: func(conn net.Conn, r *bufio.Reader) error {
+		if err := writeAll(conn, "220-mail.example.com ESMTP\r\n220 ready\r\n"); err != nil {
+			return err
+		}
+		if err := expectLine(r, "EHLO labtime"); err != nil {
+			return err
+		}
+		if err := writeAll(conn, "250-mail.example.com\r\n250-STARTTLS\r\n250 8BITMIME\r\n"); err != nil {
+			return err
+		}
+		if err := expectLine(r, "STARTTLS"); err != nil {
+			return err
+		}
+		return writeAll(conn, "220 go ahead\r\n")
+	},
+	startTLSIMAP: func(conn net.Conn, r *bufio.Reader) error {
+		if err := writeAll(conn, "* OK IMAP4rev1 ready\r\n"); err != nil {
+			return err
+		}
+		if err := expectLine(r, "labtime1 STARTTLS"); err != nil {
+			return err
+		}
+		return writeAll(conn, "* CAPABILITY IMAP4rev1\r\nlabtime1 OK begin TLS\r\n")
+	},
+	startTLSPOP3: func(conn net.Conn, r *bufio.Reader) error {
+		if err := writeAll(conn, "+OK POP3 ready\r\n"); err != nil {
+			return err
+		}
+		if err := expectLine(r, "STLS"); err != nil {
+			return err
+		}
+		return writeAll(conn, "+OK begin TLS\r\n")
+	},
+	startTLSFTP: func(conn net.Conn, r *bufio.Reader) error {
+		if err := writeAll(conn, "220 FTP ready\r\n"); err != nil {
+			return err
+		}
+		if err := expectLine(r, "AUTH TLS"); err != nil {
+			return err
+		}
+		return writeAll(conn, "234 AUTH TLS successful\r\n")
+	},
+	startTLSXMPP: func(conn net.Conn, r *bufio.Reader) error {
+		header, err := readUntil(r, "version='1.0'>")
+		if err != nil {
+			return err
+		}
+		if !strings.Contains(header, "to='example.com'") {
+			return errors.New("unexpected stream header " + header)
+		}
+		if err := writeAll(conn, "<?xml version='1.0'?><stream:stream from='example.com' version='1.0'>"+
+			"<stream:features><starttls xmlns='urn:ietf:params:xml:ns:xmpp-tls'><required/></starttls></stream:features>"); err != nil {
+			return err
+		}
+		if _, err := readUntil(r, "/>"); err != nil {
+			return err
+		}
+		return writeAll(conn, "<proceed xmlns='urn:ietf:params:xml:ns:xmpp-tls'/>")
+	},
+	startTLSLDAP: func(conn net.Conn, r *bufio.Reader) error {
+		request := make([]byte, len(ldapStartTLSRequest))
+		if _, err := io.ReadFull(r, request); err != nil {
+			return err
+		}
+		if !bytes.Equal(request, ldapStartTLSRequest) {
+			return errors.New("unexpected extended request")
+		}
+		// ExtendedResponse with the success result code.
+		_, err := conn.Write([]byte{0x30, 0x0c, 0x02, 0x01, 0x01, 0x78, 0x07, 0x0a, 0x01, 0x00, 0x04, 0x00, 0x04, 0x00})
+		return err
+	},
+	startTLSPostgres: func(conn net.Conn, r *bufio.Reader) error {
+		request := make([]byte, 8)
+		if _, err := io.ReadFull(r, request); err != nil {
+			return err
+		}
+		if !bytes.Equal(request, []byte{0, 0, 0, 8, 0x04, 0xd2, 0x16, 0x2f}) {
+			return errors.New("unexpected SSLRequest")
+		}
+		return writeAll(conn, "S")
+	},
+}
+
+// startFakeStartTLSServer serves the STARTTLS negotiation then the TLS
+// handshake on the server side of a pipe. It returns the client side.
+func startFakeStartTLSServer(t *testing.T, serve fakeStartTLSServer, certificate tls.Certificate) net.Conn {
+	t.Helper()
+
+	client, server := net.Pipe()
+	t.Cleanup(func() {
+		_ = client.Close()
+		_ = server.Close()
+	})
+	_ = server.SetDeadline(time.Now().Add(10 * time.Second))
+
+	go func() {
+		defer server.Close()
+		if err := serve(server, bufio.NewReader(server)); err != nil {
+			return
+		}
+		tlsConn := tls.Server(server, &tls.Config{
+			Certificates: []tls.Certificate{certificate},
+			MinVersion:   tls.VersionTLS12,
+		})
+		_ = tlsConn.Handshake()
+	}()
+
+	return client
+}
+
+func TestTLSMonitor_tlsHandshake_StartTLS(t *testing.T) {
+	ca := newTestCertificate(t, &x509.Certificate{IsCA: true}, nil)
+	notAfter := time.Now().Add(72 * time.Hour).Truncate(time.Second)
+	leaf := newTestCertificate(t, &x509.Certificate{DNSNames: []string{"example.com"}, NotAfter: notAfter}, ca)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Cert)
+
+	for protocol, serve := range fakeStartTLSServers {
+		t.Run(protocol, func(t *testing.T) {
+			conn := startFakeStartTLSServer(t, serve, leaf.TLSCertificate())
+
+			monitor := &TLSMonitor{
+				Label:    protocol,
+				Domain:   "example.com",
+				Port:     startTLSDefaultPorts[protocol],
+				StartTLS: protocol,
+				RootCAs:  roots,
+				Logger:   log.New(bytes.NewBuffer(nil), "", 0),
+				NetDialFunc: func(_ context.Context, _, addr string) (net.Conn, error) {
+					if _, port, _ := net.SplitHostPort(addr); port == "443" {
+						t.Errorf("Unexpected default port in %s", addr)
+					}
+					return conn, nil
+				},
+			}
+
+			data, err := monitor.tlsHandshake(t.Context())
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !data.Expires.Equal(notAfter) {
+				t.Errorf("Expected expiration %s, got %s", notAfter, data.Expires)
+			}
+		})
+	}
+}
+
+func TestStartTLS_Refused(t *testing.T) {
+	tests := map[string]fakeStartTLSServer{
+		startTLSSMTP: func(conn net.Conn, r *bufio.Reader) error {
+			if err := writeAll(conn, "220 ready\r\n"); err != nil {
+				return err
+			}
+			if err := expectLine(r, "EHLO labtime"); err != nil {
+				return err
+			}
+			if err := writeAll(conn, "250 mail.example.com\r\n"); err != nil {
+				return err
+			}
+			if err := expectLine(r, "STARTTLS"); err != nil {
+				return err
+			}
+			return writeAll(conn, "454 TLS not available\r\n")
+		},
+		startTLSIMAP: func(conn net.Conn, r *bufio.Reader) error {
+			if err := writeAll(conn, "* OK ready\r\n"); err != nil {
+				return err
+			}
+			if err := expectLine(r, "labtime1 STARTTLS"); err != nil {
+				return err
+			}
+			return writeAll(conn, "labtime1 BAD unknown command\r\n")
+		},
+		startTLSLDAP: func(conn net.Conn, r *bufio.Reader) error {
+			if _, err := io.ReadFull(r, make([]byte, len(ldapStartTLSRequest))); err != nil {
+				return err
+			}
+			// protocolError (2) result code.
+			_, err := conn.Write([]byte{0x30, 0x0c, 0x02, 0x01, 0x01, 0x78, 0x07, 0x0a, 0x01, 0x02, 0x04, 0x00, 0x04, 0x00})
+			return err
+		},
+		startTLSPostgres: func(conn net.Conn, r *bufio.Reader) error {
+			if _, err := io.ReadFull(r, make([]byte, 8)); err != nil {
+				return err
+			}
+			return writeAll(conn, "N")
+		},
+	}
+
+	for protocol, serve := range tests {
+		t.Run(protocol, func(t *testing.T) {
+			client, server := net.Pipe()
+			defer client.Close()
+			_ = client.SetDeadline(time.Now().Add(10 * time.Second))
+			go func() {
+				defer server.Close()
+				_ = serve(server, bufio.NewReader(server))
+			}()
+
+			err := startTLS(client, protocol, "example.com")
+			if !errors.Is(err, ErrStartTLS) {
+				t.Errorf("Expected ErrStartTLS, got %v", err)
+			}
+		})
+	}
+}
+
+func TestTLSTargetProvider_GetTargets_StartTLS(t *testing.T) {
+	provider := TLSTargetProvider{}
+
+	targets, err := provider.GetTargets(&yamlconfig.YamlConfig{
+		TLSMonitors: []yamlconfig.TLSMonitorDTO{
+			{Domain: "mail.example.com", StartTLS: "smtp"},
+			{Domain: "mail.example.com", StartTLS: "smtp", Port: 587},
+		},
+	})
+	if err != nil {
+		t.Fatalf("GetTargets() returned unexpected error: %v", err)
+	}
+	if targets[0].Port != 25 || targets[0].StartTLS != startTLSSMTP {
+		t.Errorf("Unexpected default STARTTLS target: %+v", targets[0])
+	}
+	if targets[1].Port != 587 {
+		t.Errorf("Expected port 587, got %d", targets[1].Port)
+	}
+
+	if _, err := provider.GetTargets(&yamlconfig.YamlConfig{
+		TLSMonitors: []yamlconfig.TLSMonitorDTO{{Domain: "example.com", StartTLS: "gopher"}},
+	}); err == nil {
+		t.Error("Expected error for invalid starttls but got none")
+	}
+}
@@ -0,0 +1,65 @@
+package monitors
+
+import (
+	"crypto/x509"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Reasons reported by the labtime_tls_cert_valid metric when a certificate is
+// invalid.
+const (
+	tlsInvalidReasonExpired          = "expired"
+	tlsInvalidReasonNotYetValid      = "not_yet_valid"
+	tlsInvalidReasonUnknownAuthority = "unknown_authority"
+	tlsInvalidReasonHostnameMismatch = "hostname_mismatch"
+	tlsInvalidReasonOther            = "invalid"
+)
+
+// verifyCertificates verifies the chain presented by the server, leaf first,
+// against the roots (nil uses the system roots) and the server name.
+func verifyCertificates(certs []*x509.Certificate, roots *x509.CertPool, serverName string, now time.Time) error {
+	if len(certs) == 0 {
+		return errors.New("no certificate presented by the server")
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		DNSName:       serverName,
+		CurrentTime:   now,
+	})
+	return err
+}
+
+// tlsInvalidReason classifies a verification error into a
+// labtime_tls_cert_valid reason.
+func tlsInvalidReason(err error, now time.Time) string {
+	var (
+		invalidErr   x509.CertificateInvalidError
+		authorityErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+	)
+
+	switch {
+	case errors.As(err, &invalidErr) && invalidErr.Reason == x509.Expired:
+		// The error does not tell which bound of the validity period is
+		// exceeded.
+		if invalidErr.Cert != nil && now.Before(invalidErr.Cert.NotBefore) {
+			return tlsInvalidReasonNotYetValid
+		}
+		return tlsInvalidReasonExpired
+	case errors.As(err, &authorityErr):
+		return tlsInvalidReasonUnknownAuthority
+	case errors.As(err, &hostnameErr):
+		return tlsInvalidReasonHostnameMismatch
+	default:
+		return tlsInvalidReasonOther
+	}
+}
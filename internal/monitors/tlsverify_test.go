@@ -0,0 +1,146 @@
+package monitors
+
+import (
+	"bytes"
+	"crypto/x509"
+	"log"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestVerifyCertificates(t *testing.T) {
+	now := time.Now()
+	ca := newTestCertificate(t, &x509.Certificate{IsCA: true}, nil)
+	expiredIntermediate := newTestCertificate(t, &x509.Certificate{
+		IsCA:      true,
+		NotBefore: now.Add(-48 * time.Hour),
+		NotAfter:  now.Add(-time.Hour),
+	}, ca)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Cert)
+
+	tests := []struct {
+		name           string
+		chain          func() []*x509.Certificate
+		roots          *x509.CertPool
+		expectedReason string
+	}{
+		{
+			name: "valid",
+			chain: func() []*x509.Certificate {
+				return []*x509.Certificate{newTestCertificate(t, &x509.Certificate{DNSNames: []string{"example.com"}}, ca).Cert}
+			},
+			roots: roots,
+		},
+		{
+			name: "expired",
+			chain: func() []*x509.Certificate {
+				return []*x509.Certificate{newTestCertificate(t, &x509.Certificate{
+					DNSNames:  []string{"example.com"},
+					NotBefore: now.Add(-48 * time.Hour),
+					NotAfter:  now.Add(-time.Hour),
+				}, ca).Cert}
+			},
+			roots:          roots,
+			expectedReason: tlsInvalidReasonExpired,
+		},
+		{
+			name: "not yet valid",
+			chain: func() []*x509.Certificate {
+				return []*x509.Certificate{newTestCertificate(t, &x509.Certificate{
+					DNSNames:  []string{"example.com"},
+					NotBefore: now.Add(time.Hour),
+					NotAfter:  now.Add(48 * time.Hour),
+				}, ca).Cert}
+			},
+			roots:          roots,
+			expectedReason: tlsInvalidReasonNotYetValid,
+		},
+		{
+			name: "unknown authority",
+			chain: func() []*x509.Certificate {
+				return []*x509.Certificate{newTestCertificate(t, &x509.Certificate{DNSNames: []string{"example.com"}}, nil).Cert}
+			},
+			roots:          roots,
+			expectedReason: tlsInvalidReasonUnknownAuthority,
+		},
+		{
+			name: "hostname mismatch",
+			chain: func() []*x509.Certificate {
+				return []*x509.Certificate{newTestCertificate(t, &x509.Certificate{DNSNames: []string{"other.example.com"}}, ca).Cert}
+			},
+			roots:          roots,
+			expectedReason: tlsInvalidReasonHostnameMismatch,
+		},
+		{
+			name: "expired intermediate",
+			chain: func() []*x509.Certificate {
+				leaf := newTestCertificate(t, &x509.Certificate{DNSNames: []string{"example.com"}}, expiredIntermediate)
+				return []*x509.Certificate{leaf.Cert, expiredIntermediate.Cert}
+			},
+			roots:          roots,
+			expectedReason: tlsInvalidReasonExpired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyCertificates(tt.chain(), tt.roots, "example.com", now)
+			if tt.expectedReason == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Expected error but got none")
+			}
+			if reason := tlsInvalidReason(err, now); reason != tt.expectedReason {
+				t.Errorf("Expected reason %s, got %s (%v)", tt.expectedReason, reason, err)
+			}
+		})
+	}
+}
+
+func TestTLSMonitor_Run_InvalidCertificate(t *testing.T) {
+	notAfter := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	selfSigned := newTestCertificate(t, &x509.Certificate{DNSNames: []string{"example.com"}, NotAfter: notAfter}, nil)
+	addr := newTestTLSListener(t, selfSigned.TLSCertificate())
+	host, port, _ := net.SplitHostPort(addr)
+	portNumber, _ := strconv.Atoi(port)
+
+	collector := TLSMonitorFactory{}.CreateCollector()
+	monitor := TLSMonitorFactory{}.CreateMonitor(TLSTarget{
+		Name:    "self-signed",
+		Domain:  "example.com",
+		Port:    portNumber,
+		Address: host,
+	}, collector, log.New(bytes.NewBuffer(nil), "", 0))
+
+	if err := monitor.Run(t.Context()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	labels := prometheus.Labels{"tls_monitor_name": "self-signed", "tls_domain_name": "example.com"}
+	valid := testutil.ToFloat64(collector.Valid.MustCurryWith(labels).WithLabelValues(tlsInvalidReasonUnknownAuthority))
+	if valid != 0 {
+		t.Errorf("Expected invalid certificate, got %f", valid)
+	}
+	if count := testutil.CollectAndCount(collector, "labtime_tls_cert_valid"); count != 1 {
+		t.Errorf("Expected 1 validity series, got %d", count)
+	}
+
+	// The expiration is still published.
+	expires := testutil.ToFloat64(collector.ExpiresTime.With(labels))
+	if expires <= 0 || expires > time.Until(notAfter).Seconds()+1 {
+		t.Errorf("Unexpected expiration %f", expires)
+	}
+	if count := testutil.CollectAndCount(collector, "labtime_tls_connection_info"); count != 1 {
+		t.Errorf("Expected 1 connection info series, got %d", count)
+	}
+}
@@ -0,0 +1,12 @@
+package yamlconfig
+
+// CertFilesMonitorDTO represents the configuration for certificate files monitoring targets.
+type CertFilesMonitorDTO struct {
+	// Name of the target. Used to identify the target from Prometheus. Default is the first path.
+	Name string `yaml:"name,omitempty" json:"name,omitempty"`
+	// Paths of the certificate files. Shell patterns (e.g. /etc/ssl/private/*.pem) are supported.
+	// PEM and DER certificates and bundles are read, as well as the certificates of Traefik acme.json files.
+	Paths []string `yaml:"paths" json:"paths"`
+	// Interval to read the files. Default is 60 seconds.
+	Interval int `yaml:"interval,omitempty" json:"interval,omitempty"`
+}
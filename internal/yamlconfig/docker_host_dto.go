@@ -0,0 +1,12 @@
+package yamlconfig
+
+// DockerHostDTO represents a Docker daemon the Docker monitors can connect to.
+type DockerHostDTO struct {
+	// Name of the Docker host. Referenced by the host of the Docker monitors and exported as the docker_host label.
+	Name string `yaml:"name" json:"name"`
+	// URL of the Docker daemon (tcp://host:2376, ssh://user@host or unix:///var/run/docker.sock). The ssh scheme
+	// requires the ssh command and the docker command on the remote host.
+	Host string `yaml:"host" json:"host"`
+	// TLS client settings used to connect to a tcp:// daemon.
+	TLS *HTTPTLSDTO `yaml:"tls,omitempty" json:"tls,omitempty"`
+}
@@ -2,10 +2,30 @@ package yamlconfig
 
 // DockerMonitorDTO represents the configuration for Docker container monitoring targets.
 type DockerMonitorDTO struct {
-	// Name of the target. Used to identify the target from Prometheus. Default is the container name.
+	// Name of the target. Used to identify the target from Prometheus. Default is the container name, the
+	// compose service, the compose project or the name pattern. Required when selecting by labels only.
 	Name string `yaml:"name,omitempty" json:"name,omitempty"`
+	// Name of the Docker host, from the docker_hosts section, running the containers. Default is the local Docker
+	// daemon configured from the environment.
+	Host string `yaml:"host,omitempty" json:"host,omitempty"`
 	// Container name to monitor. Should match the exact container name in Docker.
-	ContainerName string `yaml:"container_name" json:"container_name"`
+	ContainerName string `yaml:"container_name,omitempty" json:"container_name,omitempty"`
+	// Labels the monitored containers must have. An empty value only requires the label to be set.
+	Labels map[string]string `yaml:"labels,omitempty" json:"labels,omitempty"`
+	// Docker Compose project of the monitored containers (com.docker.compose.project label).
+	ComposeProject string `yaml:"compose_project,omitempty" json:"compose_project,omitempty"`
+	// Docker Compose service of the monitored containers (com.docker.compose.service label).
+	ComposeService string `yaml:"compose_service,omitempty" json:"compose_service,omitempty"`
+	// Regular expression the monitored container names must match.
+	NamePattern string `yaml:"name_pattern,omitempty" json:"name_pattern,omitempty"`
+	// Number of running containers expected among the selected containers. Default is no expectation.
+	ExpectedReplicas int `yaml:"expected_replicas,omitempty" json:"expected_replicas,omitempty"`
+	// Number of restarts within the crash loop window flagging the container as crash looping. Default is 3.
+	CrashLoopRestarts int `yaml:"crash_loop_restarts,omitempty" json:"crash_loop_restarts,omitempty"`
+	// Crash loop window in seconds. Default is 300 seconds.
+	CrashLoopWindow int `yaml:"crash_loop_window,omitempty" json:"crash_loop_window,omitempty"`
+	// Export the CPU, memory, network and block IO usage of the container. Default is false.
+	Stats bool `yaml:"stats,omitempty" json:"stats,omitempty"`
 	// Interval to check the container status. Default is 60 seconds.
 	Interval int `yaml:"interval,omitempty" json:"interval,omitempty"`
 }
@@ -6,9 +6,136 @@ type HTTPMonitorDTO struct {
 	Name string `yaml:"name,omitempty" json:"name,omitempty"`
 	// URL of the target. The target should be accessible from the machine running the exporter.
 	// The URL should contain the protocol (http:// or https://) and the port if it's not the default one.
+	// Targets served over a Unix domain socket use the unix://<socket path>:<request path> form
+	// (e.g. unix:///run/app.sock:/health).
 	URL string `yaml:"url" json:"url"`
-	// Method is the HTTP method to use for the request. Default is HEAD.
+	// Method is the HTTP method to use for the request. Default is HEAD, or GET when assertions or
+	// content_change are set.
 	Method string `yaml:"method,omitempty" json:"method,omitempty"`
 	// Interval to ping the target. Default is 60 seconds.
 	Interval int `yaml:"interval,omitempty" json:"interval,omitempty"`
+	// Timeout of the request in seconds, including the body transfer. Default is 10 seconds.
+	Timeout int `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	// Follow the HTTP redirects. Default is true.
+	FollowRedirects *bool `yaml:"follow_redirects,omitempty" json:"follow_redirects,omitempty"`
+	// Maximum number of redirects followed before failing. Default is 10.
+	MaxRedirects int `yaml:"max_redirects,omitempty" json:"max_redirects,omitempty"`
+	// Disable the HTTP keep-alives, a new connection is opened for each check.
+	DisableKeepAlives bool `yaml:"disable_keepalives,omitempty" json:"disable_keepalives,omitempty"`
+	// IP protocol used to reach the target: ipv4, ipv6 or both. Default is both.
+	IPProtocol string `yaml:"ip_protocol,omitempty" json:"ip_protocol,omitempty"`
+	// Probe each resolved address of the target host individually. Metrics are labelled with the address.
+	ProbeAllAddresses bool `yaml:"probe_all_addresses,omitempty" json:"probe_all_addresses,omitempty"`
+	// URL of the proxy used to reach the target (http://, https://, socks5:// or socks5h://). Credentials can
+	// be set in the URL. When not set, the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables apply.
+	ProxyURL string `yaml:"proxy_url,omitempty" json:"proxy_url,omitempty"`
+	// Hosts, domains (.example.com), IP addresses or CIDR ranges reached without the proxy.
+	NoProxy []string `yaml:"no_proxy,omitempty" json:"no_proxy,omitempty"`
+	// List of expected status codes. Each entry can be a status code (200), a class (2xx), a range (200-299)
+	// or a comma separated list of those (200-299,401). Default is 2xx.
+	ExpectedStatus []string `yaml:"expected_status,omitempty" json:"expected_status,omitempty"`
+	// HTTP headers added to the request.
+	Headers map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
+	// Body sent with the request. Mutually exclusive with BodyFile.
+	Body string `yaml:"body,omitempty" json:"body,omitempty"`
+	// Path of a file whose content is sent as the request body. Mutually exclusive with Body.
+	BodyFile string `yaml:"body_file,omitempty" json:"body_file,omitempty"`
+	// HTTP basic authentication. Mutually exclusive with the other authentication methods.
+	BasicAuth *HTTPBasicAuthDTO `yaml:"basic_auth,omitempty" json:"basic_auth,omitempty"`
+	// Bearer token sent in the Authorization header. Mutually exclusive with the other authentication methods.
+	BearerToken string `yaml:"bearer_token,omitempty" json:"bearer_token,omitempty"`
+	// Path of a file containing the bearer token.
+	BearerTokenFile string `yaml:"bearer_token_file,omitempty" json:"bearer_token_file,omitempty"`
+	// Name of an environment variable containing the bearer token.
+	BearerTokenEnv string `yaml:"bearer_token_env,omitempty" json:"bearer_token_env,omitempty"`
+	// OAuth2 client credentials flow. Mutually exclusive with the other authentication methods.
+	OAuth2 *HTTPOAuth2DTO `yaml:"oauth2,omitempty" json:"oauth2,omitempty"`
+	// TLS client settings used to connect to the target.
+	TLS *HTTPTLSDTO `yaml:"tls,omitempty" json:"tls,omitempty"`
+	// Maximum number of bytes read from the response body. Default is 1048576 (1 MiB).
+	BodyMaxSize int64 `yaml:"body_max_size,omitempty" json:"body_max_size,omitempty"`
+	// List of assertions evaluated against the response body. Requires a method returning a body (not HEAD).
+	Assertions []HTTPAssertionDTO `yaml:"assertions,omitempty" json:"assertions,omitempty"`
+	// Detect changes of the response body. Requires a method returning a body (not HEAD).
+	ContentChange *HTTPContentChangeDTO `yaml:"content_change,omitempty" json:"content_change,omitempty"`
+}
+
+// HTTPContentChangeDTO represents the settings of the response body change detection.
+type HTTPContentChangeDTO struct {
+	// Regular expressions matching the volatile parts of the body (timestamps, nonces...) ignored by the
+	// detection.
+	Ignore []string `yaml:"ignore,omitempty" json:"ignore,omitempty"`
+}
+
+// HTTPAssertionDTO represents a check performed on the HTTP response body.
+// Exactly one of Contains, NotContains, Regex or JSONPath must be set.
+type HTTPAssertionDTO struct {
+	// Name of the assertion. Used to identify the assertion from Prometheus.
+	Name string `yaml:"name" json:"name"`
+	// The body must contain this substring.
+	Contains string `yaml:"contains,omitempty" json:"contains,omitempty"`
+	// The body must not contain this substring.
+	NotContains string `yaml:"not_contains,omitempty" json:"not_contains,omitempty"`
+	// The body must match this regular expression.
+	Regex string `yaml:"regex,omitempty" json:"regex,omitempty"`
+	// gjson path expression evaluated against the JSON body (e.g. "status" or "checks.#(name==\"db\").ok").
+	// Without comparison, the path must exist.
+	JSONPath string `yaml:"json_path,omitempty" json:"json_path,omitempty"`
+	// The value at JSONPath must be equal to this string.
+	Equals string `yaml:"equals,omitempty" json:"equals,omitempty"`
+	// The value at JSONPath must not be equal to this string.
+	NotEquals string `yaml:"not_equals,omitempty" json:"not_equals,omitempty"`
+	// The value at JSONPath must be a number greater than this value.
+	GreaterThan *float64 `yaml:"greater_than,omitempty" json:"greater_than,omitempty"`
+	// The value at JSONPath must be a number less than this value.
+	LessThan *float64 `yaml:"less_than,omitempty" json:"less_than,omitempty"`
+}
+
+// HTTPBasicAuthDTO represents the HTTP basic authentication credentials.
+// Only one of Password, PasswordFile or PasswordEnv can be set.
+type HTTPBasicAuthDTO struct {
+	// Username sent to the target.
+	Username string `yaml:"username" json:"username"`
+	// Password sent to the target.
+	Password string `yaml:"password,omitempty" json:"password,omitempty"`
+	// Path of a file containing the password.
+	PasswordFile string `yaml:"password_file,omitempty" json:"password_file,omitempty"`
+	// Name of an environment variable containing the password.
+	PasswordEnv string `yaml:"password_env,omitempty" json:"password_env,omitempty"`
+}
+
+// HTTPOAuth2DTO represents the OAuth2 client credentials flow configuration.
+// Only one of ClientSecret, ClientSecretFile or ClientSecretEnv can be set.
+type HTTPOAuth2DTO struct {
+	// URL of the token endpoint.
+	TokenURL string `yaml:"token_url" json:"token_url"`
+	// Client ID of the application.
+	ClientID string `yaml:"client_id" json:"client_id"`
+	// Client secret of the application.
+	ClientSecret string `yaml:"client_secret,omitempty" json:"client_secret,omitempty"`
+	// Path of a file containing the client secret.
+	ClientSecretFile string `yaml:"client_secret_file,omitempty" json:"client_secret_file,omitempty"`
+	// Name of an environment variable containing the client secret.
+	ClientSecretEnv string `yaml:"client_secret_env,omitempty" json:"client_secret_env,omitempty"`
+	// Scopes requested with the token.
+	Scopes []string `yaml:"scopes,omitempty" json:"scopes,omitempty"`
+	// Additional parameters sent to the token endpoint.
+	EndpointParams map[string]string `yaml:"endpoint_params,omitempty" json:"endpoint_params,omitempty"`
+}
+
+// HTTPTLSDTO represents the TLS client settings of an HTTP target.
+type HTTPTLSDTO struct {
+	// Path of a PEM file containing the CA certificates used to verify the server certificate.
+	// Default is the system certificate pool.
+	CAFile string `yaml:"ca_file,omitempty" json:"ca_file,omitempty"`
+	// Path of a PEM file containing the client certificate (mutual TLS). Requires KeyFile.
+	CertFile string `yaml:"cert_file,omitempty" json:"cert_file,omitempty"`
+	// Path of a PEM file containing the client private key (mutual TLS). Requires CertFile.
+	KeyFile string `yaml:"key_file,omitempty" json:"key_file,omitempty"`
+	// Server name used for SNI and certificate verification. Default is the URL host.
+	ServerName string `yaml:"server_name,omitempty" json:"server_name,omitempty"`
+	// Disable the server certificate verification.
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify,omitempty" json:"insecure_skip_verify,omitempty"`
+	// Minimum TLS version accepted (1.0, 1.1, 1.2 or 1.3). Default is 1.2.
+	MinVersion string `yaml:"min_version,omitempty" json:"min_version,omitempty"`
 }
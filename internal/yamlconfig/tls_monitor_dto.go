@@ -5,8 +5,49 @@ type TLSMonitorDTO struct {
 	// Name of the target. Used to identify the target from Prometheus. Default is the domain name.
 	Name string `yaml:"name,omitempty" json:"name,omitempty"`
 	// Domain name address of the target. The target should be accessible from the machine running the exporter.
-	// The domain should not contain the protocol (http:// or https://) and the port (:443 is mandatory to check the TLS certificate).
+	// The domain should not contain the protocol (http:// or https://) nor the port (see the port field).
 	Domain string `yaml:"domain" json:"domain"`
+	// Port of the TLS service. Default is 443, or the default port of the STARTTLS protocol.
+	Port int `yaml:"port,omitempty" json:"port,omitempty"`
+	// Address (IP address or host name) to connect to instead of the domain, e.g. a specific backend node
+	// behind a load balancer. The certificate is still checked against the domain.
+	Address string `yaml:"address,omitempty" json:"address,omitempty"`
+	// Server name sent in the TLS handshake (SNI). Default is the domain.
+	ServerName string `yaml:"server_name,omitempty" json:"server_name,omitempty"`
+	// Upgrade a plain text connection with STARTTLS before the TLS handshake. One of smtp, imap, pop3, ldap,
+	// xmpp, ftp or postgres.
+	StartTLS string `yaml:"starttls,omitempty" json:"starttls,omitempty"`
+	// Check the revocation status of the certificate with OCSP: the stapled response, or the OCSP responder of
+	// the certificate when none is stapled.
+	OCSP bool `yaml:"ocsp,omitempty" json:"ocsp,omitempty"`
+	// Check the revocation status of the certificate with the CRL of the certificate.
+	CRL bool `yaml:"crl,omitempty" json:"crl,omitempty"`
+	// Evaluate the security policy of the service. Violations are exported per rule.
+	Policy *TLSPolicyDTO `yaml:"policy,omitempty" json:"policy,omitempty"`
+	// Expected SHA-256 fingerprint of the leaf certificate, hex (colons allowed) or base64 encoded.
+	PinnedFingerprint string `yaml:"pinned_fingerprint,omitempty" json:"pinned_fingerprint,omitempty"`
+	// Expected SHA-256 hash of the leaf certificate public key (SubjectPublicKeyInfo), hex (colons allowed)
+	// or base64 encoded as in HPKP pins. Unlike the fingerprint, it survives renewals reusing the key.
+	PinnedPublicKey string `yaml:"pinned_public_key,omitempty" json:"pinned_public_key,omitempty"`
+	// Timeout of the TLS handshake in seconds, including the connection and the STARTTLS negotiation.
+	// Default is 10 seconds.
+	Timeout int `yaml:"timeout,omitempty" json:"timeout,omitempty"`
 	// Interval to ping the target. Default is 60 seconds.
 	Interval int `yaml:"interval,omitempty" json:"interval,omitempty"`
 }
+
+// TLSPolicyDTO represents the security policy evaluated against a TLS service.
+type TLSPolicyDTO struct {
+	// Key types allowed for the certificate: rsa, ecdsa or ed25519. Default is all of them.
+	AllowedKeyTypes []string `yaml:"allowed_key_types,omitempty" json:"allowed_key_types,omitempty"`
+	// Minimum size (in bits) of the RSA keys. Default is 2048.
+	MinRSAKeySize int `yaml:"min_rsa_key_size,omitempty" json:"min_rsa_key_size,omitempty"`
+	// Minimum size (in bits) of the ECDSA keys. Default is 256.
+	MinECDSAKeySize int `yaml:"min_ecdsa_key_size,omitempty" json:"min_ecdsa_key_size,omitempty"`
+	// Allow SHA-1 signatures in the certificate chain. Default is false.
+	AllowSHA1 bool `yaml:"allow_sha1,omitempty" json:"allow_sha1,omitempty"`
+	// Probe whether the service accepts TLS 1.0 and TLS 1.1 handshakes. Default is true.
+	ProbeLegacyVersions *bool `yaml:"probe_legacy_versions,omitempty" json:"probe_legacy_versions,omitempty"`
+	// Expected common name or organization of the certificate issuer.
+	ExpectedIssuer string `yaml:"expected_issuer,omitempty" json:"expected_issuer,omitempty"`
+}
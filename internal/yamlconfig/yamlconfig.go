@@ -16,6 +16,10 @@ type YamlConfig struct {
 	TLSMonitors []TLSMonitorDTO `yaml:"tls_monitors" json:"tls_monitors,omitempty"`
 	// List of Docker containers to monitor.
 	DockerMonitors []DockerMonitorDTO `yaml:"docker_monitors" json:"docker_monitors,omitempty"`
+	// List of remote Docker hosts the Docker monitors can connect to.
+	DockerHosts []DockerHostDTO `yaml:"docker_hosts" json:"docker_hosts,omitempty"`
+	// List of certificate files to monitor.
+	CertFilesMonitors []CertFilesMonitorDTO `yaml:"cert_files" json:"cert_files,omitempty"`
 }
 
 func NewYamlConfig(r io.Reader) (*YamlConfig, error) {